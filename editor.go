@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// editorEnv names the variable holding an editor command template, which takes
+// precedence over $VISUAL and $EDITOR. {file} and {line} are replaced by the
+// file to edit and the line to start on; without {file} the file is appended.
+const editorEnv = "P_EDITOR"
+
+// defaultEditors are tried in order when no editor is configured.
+var defaultEditors = []string{"vim", "nano", "vi"} // Easy to add more, like "emacs"
+
+// externalEditor is a prompts.ContentProvider that opens the user's editor on a temporary file.
+type externalEditor struct {
+	in       io.Reader
+	out, err io.Writer
+	line     int // line to open the file at; 0 means the first
+}
+
+// Content opens an external editor to capture prompt content.
+func (e externalEditor) Content(initialContent string) (string, error) {
+	return editorContent(e.Edit(initialContent))
+}
+
+// Edit opens an external editor on initialContent. Quitting without modifying
+// the file, or emptying it, counts as cancelling.
+func (e externalEditor) Edit(initialContent string) (string, editResult, error) {
+	template, err := editorCommand()
+	if err != nil {
+		return "", editCancelled, err
+	}
+
+	// The .md suffix lets editors apply Markdown highlighting
+	tmpfile, err := os.CreateTemp(os.TempDir(), "p-prompt-*.md")
+	if err != nil {
+		return "", editCancelled, fmt.Errorf("could not create temporary file: %w", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.WriteString(initialContent); err != nil {
+		return "", editCancelled, fmt.Errorf("could not write to temporary file: %w", err)
+	}
+	if err := tmpfile.Close(); err != nil {
+		return "", editCancelled, fmt.Errorf("could not close temporary file: %w", err)
+	}
+
+	args := expandEditorArgs(template, tmpfile.Name(), max(e.line, 1))
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = e.in
+	cmd.Stdout = e.out
+	cmd.Stderr = e.err
+
+	if err := cmd.Run(); err != nil {
+		return "", editCancelled, fmt.Errorf("editor command failed: %w", err)
+	}
+
+	editedContentBytes, err := os.ReadFile(tmpfile.Name())
+	if err != nil {
+		return "", editCancelled, fmt.Errorf("could not read edited content from temporary file: %w", err)
+	}
+
+	edited := string(editedContentBytes)
+	if edited == initialContent || strings.TrimSpace(edited) == "" {
+		return "", editCancelled, nil
+	}
+	return edited, editSaved, nil
+}
+
+// editorCommand returns the configured editor command split into words, trying
+// $P_EDITOR, $VISUAL and $EDITOR before falling back to defaultEditors.
+func editorCommand() ([]string, error) {
+	for _, env := range []string{editorEnv, "VISUAL", "EDITOR"} {
+		value := strings.TrimSpace(os.Getenv(env))
+		if value == "" {
+			continue
+		}
+		words, err := splitShellWords(value)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", env, err)
+		}
+		return words, nil
+	}
+	for _, e := range defaultEditors {
+		if path, err := exec.LookPath(e); err == nil {
+			return []string{path}, nil
+		}
+	}
+	return nil, fmt.Errorf("no editor configured in %s, VISUAL or EDITOR and no default editor (%s) found", editorEnv, strings.Join(defaultEditors, ", "))
+}
+
+// expandEditorArgs fills the {file} and {line} placeholders in an editor
+// command, appending file when the command has no {file}.
+func expandEditorArgs(template []string, file string, line int) []string {
+	r := strings.NewReplacer("{file}", file, "{line}", strconv.Itoa(line))
+	args := make([]string, len(template))
+	hasFile := false
+	for i, word := range template {
+		hasFile = hasFile || strings.Contains(word, "{file}")
+		args[i] = r.Replace(word)
+	}
+	if !hasFile {
+		args = append(args, file)
+	}
+	return args
+}
+
+// splitShellWords splits s into words the way a POSIX shell would, honouring
+// single quotes, double quotes and backslash escapes but not expansions.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			// Inside double quotes a backslash only escapes characters special there
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", r) {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yookibooki/p/prompts"
+)
+
+// mcpProtocolVersion is the Model Context Protocol revision this server implements.
+const mcpProtocolVersion = "2025-06-18"
+
+// JSON-RPC 2.0 error codes used by the MCP server.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// rpcRequest is an incoming JSON-RPC message. Notifications have no ID.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is an outgoing JSON-RPC response carrying either a result or an error.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// mcpTextContent builds a single text content block.
+func mcpTextContent(text string) []map[string]string {
+	return []map[string]string{{"type": "text", "text": text}}
+}
+
+// mcpTools describes the tools the server offers, with JSON Schemas for their arguments.
+var mcpTools = []map[string]any{
+	{
+		"name":        "search_prompts",
+		"description": "Search the prompt library by text in the name or body, optionally filtered by tags.",
+		"inputSchema": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{"type": "string", "description": "Text to look for in prompt names and bodies"},
+				"tags":  map[string]any{"type": "string", "description": "Comma-separated tags (OR), or AND:tag1,tag2"},
+			},
+		},
+	},
+	{
+		"name":        "add_prompt",
+		"description": "Add a new prompt to the library.",
+		"inputSchema": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":   map[string]any{"type": "string"},
+				"prompt": map[string]any{"type": "string", "description": "Prompt body; {{name}} marks a template variable"},
+				"tags":   map[string]any{"type": "string", "description": "Comma-separated tags"},
+			},
+			"required": []string{"name", "prompt"},
+		},
+	},
+	{
+		"name":        "update_prompt",
+		"description": "Update the body and/or tags of an existing prompt.",
+		"inputSchema": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":   map[string]any{"type": "string"},
+				"prompt": map[string]any{"type": "string"},
+				"tags":   map[string]any{"type": "string", "description": "Comma-separated tags, replacing the current ones"},
+			},
+			"required": []string{"name"},
+		},
+	},
+}
+
+// mcpServer answers Model Context Protocol requests from the prompt library.
+type mcpServer struct {
+	app *prompts.App
+}
+
+// serve reads newline-delimited JSON-RPC messages from r and writes responses to w until r is exhausted.
+func (s *mcpServer) serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*prompts.MaxPromptContentLen)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req rpcRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			if err := enc.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.ID == nil {
+			// Notifications such as notifications/initialized need no reply
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+		result, err := s.handle(req.Method, req.Params)
+		if err != nil {
+			rpcErr, ok := err.(*rpcError)
+			if !ok {
+				rpcErr = &rpcError{Code: rpcInternalError, Message: err.Error()}
+			}
+			resp.Error = rpcErr
+		} else {
+			resp.Result = result
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// handle dispatches a single request to its method.
+func (s *mcpServer) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": mcpProtocolVersion,
+			"capabilities": map[string]any{
+				"prompts": map[string]any{},
+				"tools":   map[string]any{},
+			},
+			"serverInfo": map[string]string{"name": "p", "version": Version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "prompts/list":
+		return s.listPrompts()
+	case "prompts/get":
+		var p struct {
+			Name      string            `json:"name"`
+			Arguments map[string]string `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		return s.getPrompt(p.Name, p.Arguments)
+	case "tools/list":
+		return map[string]any{"tools": mcpTools}, nil
+	case "tools/call":
+		var p struct {
+			Name      string            `json:"name"`
+			Arguments map[string]string `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		return s.callTool(p.Name, p.Arguments)
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method '%s' not found", method)}
+}
+
+// mcpDescription returns a prompt's description followed by its tags, falling
+// back to its name when it has neither.
+func mcpDescription(p prompts.Prompt) string {
+	tags := strings.ReplaceAll(p.Tags, ",", ", ")
+	switch {
+	case p.Description != "" && tags != "":
+		return p.Description + " (Tags: " + tags + ")"
+	case p.Description != "":
+		return p.Description
+	case tags != "":
+		return "Tags: " + tags
+	}
+	return p.Name
+}
+
+// listPrompts publishes every stored prompt with its template variables as required arguments.
+func (s *mcpServer) listPrompts() (any, error) {
+	list, err := s.app.ListPrompts("")
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]any, len(list))
+	for i, p := range list {
+		args := []map[string]any{}
+		for _, v := range prompts.TemplateVariables(p.Prompt) {
+			args = append(args, map[string]any{"name": v, "required": true})
+		}
+		result[i] = map[string]any{"name": p.Name, "description": mcpDescription(p), "arguments": args}
+	}
+	return map[string]any{"prompts": result}, nil
+}
+
+// getPrompt renders a prompt with the client's arguments as a single user message.
+func (s *mcpServer) getPrompt(name string, args map[string]string) (any, error) {
+	p, err := s.app.FindPrompt(name)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("prompt '%s' not found", name)}
+	}
+	rendered, err := prompts.Render(p.Prompt, args)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	// Usage is only for ranking, so failing to record it does not fail the request
+	_ = s.app.RecordUsage(p.Name, prompts.UsageRender)
+	return map[string]any{
+		"description": mcpDescription(*p),
+		"messages": []map[string]any{
+			{"role": "user", "content": map[string]string{"type": "text", "text": rendered}},
+		},
+	}, nil
+}
+
+// callTool runs a tool. Tool failures are reported in the result so the model can see them.
+func (s *mcpServer) callTool(name string, args map[string]string) (any, error) {
+	text, err := s.runTool(name, args)
+	if err != nil {
+		if rpcErr, ok := err.(*rpcError); ok {
+			return nil, rpcErr
+		}
+		return map[string]any{"content": mcpTextContent(err.Error()), "isError": true}, nil
+	}
+	return map[string]any{"content": mcpTextContent(text), "isError": false}, nil
+}
+
+func (s *mcpServer) runTool(name string, args map[string]string) (string, error) {
+	switch name {
+	case "search_prompts":
+		list, err := s.app.FilterPrompts(prompts.PromptFilter{Tags: args["tags"], Text: args["query"]})
+		if err != nil {
+			return "", err
+		}
+		var buf strings.Builder
+		if err := prompts.WritePrompts(&buf, prompts.FormatJSON, list, false); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	case "add_prompt":
+		if _, err := s.app.CreatePrompt(args["name"], args["prompt"], args["tags"]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Added prompt '%s'", args["name"]), nil
+	case "update_prompt":
+		existing, err := s.app.GetPrompt(args["name"])
+		if err != nil {
+			return "", err
+		}
+		content, tags := existing.Prompt, existing.Tags
+		if v, ok := args["prompt"]; ok {
+			content = v
+		}
+		if v, ok := args["tags"]; ok {
+			tags = v
+		}
+		changed, err := s.app.EditPrompt(existing, content, tags)
+		if err != nil {
+			return "", err
+		}
+		if !changed {
+			return fmt.Sprintf("Prompt '%s' is unchanged", args["name"]), nil
+		}
+		return fmt.Sprintf("Updated prompt '%s'", args["name"]), nil
+	}
+	return "", &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool '%s'", name)}
+}
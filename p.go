@@ -1,218 +1,168 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/ktr0731/go-fuzzyfinder"
 	"github.com/spf13/cobra"
+	"github.com/yookibooki/p/prompts"
 )
 
 var Version = "dev"
 
-const (
-	MaxPromptNameLen    = 255
-	MaxPromptContentLen = 10000
-)
-
-// toTagSet splits, trims, and deduplicates tags into a set.
-func toTagSet(tags string) map[string]struct{} {
-	tagSet := make(map[string]struct{})
-	for _, tag := range strings.Split(tags, ",") {
-		if trimmed := strings.TrimSpace(tag); trimmed != "" {
-			tagSet[trimmed] = struct{}{}
-		}
-	}
-	return tagSet
-}
-
-// normalizeTags deduplicates and sorts comma-separated tags, trimming whitespace.
-func normalizeTags(tags string) string {
-	if tags == "" {
-		return ""
-	}
-	tagSet := toTagSet(tags)
-	var result []string
-	for tag := range tagSet {
-		result = append(result, tag)
-	}
-	sort.Strings(result)
-	return strings.Join(result, ",")
-}
-
 // addExternalEditorFlag adds the external-editor flag to a command.
 func addExternalEditorFlag(cmd *cobra.Command) {
-	cmd.Flags().BoolP("external-editor", "e", false, "Use external editor for prompt content")
-}
-
-// validatePromptName checks if prompt name meets basic requirements.
-func validatePromptName(name string) error {
-	if name == "" {
-		return fmt.Errorf("prompt name cannot be empty")
-	}
-	if len(name) > MaxPromptNameLen {
-		return fmt.Errorf("prompt name too long (%d chars), maximum %d characters", len(name), MaxPromptNameLen)
-	}
-	return nil
-}
-
-// validatePromptContent checks if prompt content meets basic requirements.
-func validatePromptContent(content string) error {
-	trimmed := strings.TrimSpace(content)
-	if len(trimmed) == 0 {
-		return fmt.Errorf("prompt content cannot be empty")
-	}
-	if len(trimmed) > MaxPromptContentLen {
-		return fmt.Errorf("prompt content too long (%d chars), maximum %d characters", len(trimmed), MaxPromptContentLen)
-	}
-	return nil
-}
-
-// App manages prompt-related operations using a SQLite store.
-type App struct {
-	promptStore *SQLitePromptStore
-	dbPath      string
+	cmd.Flags().BoolP("external-editor", "e", false, "Use external editor ($P_EDITOR, $VISUAL or $EDITOR) for prompt content")
 }
 
-// NewApp creates a new App instance with the given prompt store and database path.
-func NewApp(store *SQLitePromptStore, dbPath string) *App {
-	return &App{promptStore: store, dbPath: dbPath}
+// addContentFlags adds the flags that choose where a command reads prompt content from.
+func addContentFlags(cmd *cobra.Command) {
+	addExternalEditorFlag(cmd)
+	cmd.Flags().StringP("content", "c", "", "Prompt content, instead of opening an editor")
+	cmd.Flags().String("file", "", "Read prompt content from a file, instead of opening an editor")
+	cmd.MarkFlagsMutuallyExclusive("content", "file", "external-editor")
 }
 
-// AddPrompt creates a new prompt using either external editor or TUI editor.
-func (a *App) AddPrompt(name, tags string, useExternalEditor bool) error {
-	if err := validatePromptName(name); err != nil {
-		return err
+// contentProvider returns where a command reads prompt content from: --content,
+// --file, the external editor, piped stdin, or the TUI editor, in that order.
+func contentProvider(cmd *cobra.Command, app *prompts.App) (prompts.ContentProvider, error) {
+	flags := cmd.Flags()
+	if flags.Changed("content") {
+		content, _ := flags.GetString("content")
+		return prompts.StringContent(content), nil
 	}
-
-	var promptContent string
-	var err error
-
-	if useExternalEditor {
-		fmt.Println("Launching external editor...")
-		promptContent, err = LaunchExternalEditor("")
-	} else {
-		promptContent, err = RunTUIEditor("")
-	}
-
-	if err != nil {
-		return fmt.Errorf("error getting prompt content: %w", err)
+	if path, _ := flags.GetString("file"); path != "" {
+		return prompts.FileContent{Path: path}, nil
 	}
-
-	if promptContent == "" {
-		fmt.Println("Operation cancelled. No prompt added.")
-		return nil
+	if useExternalEditor, _ := flags.GetBool("external-editor"); useExternalEditor {
+		fmt.Fprintln(cmd.OutOrStdout(), "Launching external editor...")
+		return externalEditor{in: cmd.InOrStdin(), out: cmd.OutOrStdout(), err: cmd.ErrOrStderr()}, nil
 	}
-
-	if err := validatePromptContent(promptContent); err != nil {
-		return err
+	if !isTerminal(cmd.InOrStdin()) {
+		return prompts.ReaderContent{R: cmd.InOrStdin()}, nil
 	}
-
-	normalizedTags := normalizeTags(tags)
-	err = a.promptStore.AddPrompt(name, promptContent, normalizedTags)
+	normalization, err := app.Normalization()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return tuiEditor{in: cmd.InOrStdin(), out: cmd.OutOrStdout(), normalization: normalization}, nil
 }
 
-// DeletePrompt removes a prompt by name from the store.
-func (a *App) DeletePrompt(name string) error {
-	err := a.promptStore.DeletePrompt(name)
-	if err != nil {
-		return fmt.Errorf("error deleting prompt: %w", err)
+// isTerminal reports whether r is an interactive terminal rather than a pipe or file.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
 	}
-	return nil
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
-// EditPrompt updates an existing prompt's content and tags.
-func (a *App) EditPrompt(existingPrompt *Prompt, newPrompt, newTags string) error {
-	if err := validatePromptName(existingPrompt.Name); err != nil {
-		return err
-	}
-
-	normalizedTags := normalizeTags(newTags)
-	// The logic to check for changes can also be simplified or moved here
-	if newPrompt == existingPrompt.Prompt && normalizedTags == existingPrompt.Tags {
-		fmt.Println("No changes detected for prompt or tags.")
-		return nil
-	}
-
-	if err := validatePromptContent(newPrompt); err != nil {
-		return err
-	}
-
-	err := a.promptStore.UpdatePrompt(existingPrompt.Name, newPrompt, normalizedTags)
-	if err != nil {
-		return fmt.Errorf("error editing prompt: %w", err)
-	}
-	return nil
+// extendContent wraps provider so its content is added after (or before) the
+// current content on a new line instead of replacing it.
+func extendContent(provider prompts.ContentProvider, prepend bool) prompts.ContentProvider {
+	return prompts.ContentFunc(func(initial string) (string, error) {
+		extra, err := provider.Content("")
+		if err != nil || extra == "" {
+			return extra, err
+		}
+		if prepend {
+			return extra + "\n" + initial, nil
+		}
+		return initial + "\n" + extra, nil
+	})
 }
 
-// ListPrompts retrieves all prompts, optionally filtered by tags.
-// Supports AND/OR logic: "tag1,tag2" (OR) or "AND:tag1,tag2" (AND)
-func (a *App) ListPrompts(tagsFilter string) ([]Prompt, error) {
-	if tagsFilter != "" {
-		return a.promptStore.ListPromptsByTags(tagsFilter)
+// parseNames splits a comma-separated --names value. A value of "-" reads
+// newline-separated names from r instead, so the output of search --multi can be piped in.
+func parseNames(value string, r io.Reader) ([]string, error) {
+	var raw []string
+	if value == "-" {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("error reading names from stdin: %w", err)
+		}
+		raw = strings.Split(string(data), "\n")
+	} else {
+		raw = strings.Split(value, ",")
 	}
-	return a.promptStore.ListPrompts()
+	var names []string
+	for _, name := range raw {
+		if trimmed := strings.TrimSpace(name); trimmed != "" {
+			names = append(names, trimmed)
+		}
+	}
+	return names, nil
 }
 
 // printPrompt formats and prints a Prompt struct to stdout.
-func printPrompt(p Prompt) {
-	fmt.Printf("Name: %s\n", p.Name)
-	fmt.Printf("Prompt: %s\n", p.Prompt)
-	fmt.Printf("Tags: %s\n", p.Tags)
-	fmt.Println("---")
+func printPrompt(w io.Writer, p prompts.Prompt) {
+	fmt.Fprintf(w, "Name: %s\n", p.Name)
+	fmt.Fprintf(w, "Prompt: %s\n", p.Prompt)
+	fmt.Fprintf(w, "Tags: %s\n", p.Tags)
+	if p.Description != "" {
+		fmt.Fprintf(w, "Description: %s\n", p.Description)
+	}
+	if p.Pinned {
+		fmt.Fprintln(w, "Pinned: yes")
+	}
+	fmt.Fprintln(w, "---")
 }
 
-// getPromptNames returns all prompt names for shell completion.
-func getPromptNames(app *App) []string {
-	prompts, err := app.ListPrompts("")
+// getPromptNames returns all prompt names for shell completion, most frecently used first.
+func getPromptNames(app *prompts.App) []string {
+	names, err := app.PromptNames()
 	if err != nil {
 		return []string{}
 	}
-	names := make([]string, len(prompts))
-	for i, p := range prompts {
-		names[i] = p.Name
-	}
 	return names
 }
 
+// completePromptName completes a prompt name one namespace segment at a time,
+// without a trailing space after a namespace so the next segment can follow.
+func completePromptName(app *prompts.App, toComplete string) ([]string, cobra.ShellCompDirective) {
+	completions := prompts.CompleteNameSegment(getPromptNames(app), toComplete)
+	directive := cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	for _, c := range completions {
+		if prompts.IsNamespace(c) {
+			directive |= cobra.ShellCompDirectiveNoSpace
+			break
+		}
+	}
+	return completions, directive
+}
+
 // getAllTags returns all unique tags for shell completion.
-func getAllTags(app *App) []string {
-	prompts, err := app.ListPrompts("")
+func getAllTags(app *prompts.App) []string {
+	counts, err := app.TagCounts()
 	if err != nil {
 		return []string{}
 	}
-	tagSet := make(map[string]struct{})
-	for _, p := range prompts {
-		if p.Tags != "" {
-			for tag := range toTagSet(p.Tags) {
-				tagSet[tag] = struct{}{}
-			}
-		}
+	tags := make([]string, len(counts))
+	for i, c := range counts {
+		tags[i] = c.Name
 	}
-	tags := make([]string, 0, len(tagSet))
-	for tag := range tagSet {
-		tags = append(tags, tag)
-	}
-	sort.Strings(tags)
 	return tags
 }
 
 func main() {
-	db, dbPath, err := InitDBWithPath()
+	db, dbPath, err := prompts.InitDBWithPath()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
 		os.Exit(1)
 	}
 	defer db.Close()
 
-	app := NewApp(NewSQLitePromptStore(db), dbPath)
+	app := prompts.NewApp(prompts.NewSQLitePromptStore(db), dbPath)
 
 	rootCmd := &cobra.Command{
 		Use:   "p",
@@ -221,24 +171,59 @@ func main() {
 
 	rootCmd.AddCommand(
 		newAddCmd(app),
+		newGetCmd(app),
 		newSearchCmd(app),
 		newDeleteCmd(app),
 		newEditCmd(app),
+		newMoveCmd(app),
+		newPinCmd(app, true),
+		newPinCmd(app, false),
 		newListCmd(app),
+		newRecentCmd(app),
+		newTopCmd(app),
 		newExportCmd(app),
 		newImportCmd(app),
 		newBackupCmd(app),
 		newRestoreCmd(app),
+		newSyncCmd(app),
+		newServeCmd(app),
+		newMCPCmd(app),
+		newConfigCmd(app),
 		newVersionCmd(),
 	)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		var queryErr *prompts.QueryError
+		if errors.As(err, &queryErr) {
+			fmt.Fprintln(os.Stderr, queryErr.Caret())
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// Exit codes, one per error class, so scripts can tell failures apart.
+const (
+	exitError     = 1
+	exitInvalid   = 2
+	exitNotFound  = 3
+	exitDuplicate = 4
+)
+
+// exitCode maps an error to the process exit code for its class.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, prompts.ErrInvalid):
+		return exitInvalid
+	case errors.Is(err, prompts.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, prompts.ErrDuplicate):
+		return exitDuplicate
 	}
+	return exitError
 }
 
-func newAddCmd(app *App) *cobra.Command {
+func newAddCmd(app *prompts.App) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "add [name]",
 		Short: "Add a new prompt",
@@ -249,20 +234,29 @@ func newAddCmd(app *App) *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("could not parse tags flag: %w", err)
 			}
-			useExternalEditor, err := cmd.Flags().GetBool("external-editor")
+
+			provider, err := contentProvider(cmd, app)
 			if err != nil {
-				return fmt.Errorf("could not parse external-editor flag: %w", err)
+				return err
+			}
+			if editor, ok := provider.(tuiEditor); ok {
+				return addWithForm(cmd, app, editor, name, tags)
 			}
 
-			if err := app.AddPrompt(name, tags, useExternalEditor); err != nil {
+			_, err = app.AddPromptWith(name, tags, provider)
+			if errors.Is(err, prompts.ErrCancelled) {
+				fmt.Fprintln(cmd.OutOrStdout(), "Operation cancelled. No prompt added.")
+				return nil
+			}
+			if err != nil {
 				return err
 			}
-			fmt.Println("Prompt added successfully!")
+			fmt.Fprintln(cmd.OutOrStdout(), "Prompt added successfully!")
 			return nil
 		},
 	}
 	cmd.Flags().StringP("tags", "t", "", "Tags for the prompt (comma-separated)")
-	addExternalEditorFlag(cmd)
+	addContentFlags(cmd)
 
 	// Add completion for tags flag
 	_ = cmd.RegisterFlagCompletionFunc("tags", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -272,39 +266,186 @@ func newAddCmd(app *App) *cobra.Command {
 	return cmd
 }
 
+// promptExists returns a check for whether a name belongs to a prompt other than except.
+func promptExists(app *prompts.App, except string) func(string) bool {
+	names := make(map[string]bool)
+	for _, name := range getPromptNames(app) {
+		names[name] = true
+	}
+	return func(name string) bool {
+		return name != except && names[name]
+	}
+}
+
+// addWithForm adds a prompt through the TUI form, prefilled with the name and tags from the command line.
+func addWithForm(cmd *cobra.Command, app *prompts.App, editor tuiEditor, name, tags string) error {
+	form, result, err := editor.Form(promptForm{Name: name, Tags: tags}, formOptions{AllTags: getAllTags(app), Exists: promptExists(app, "")})
+	if err != nil {
+		return err
+	}
+	if result == editCancelled {
+		fmt.Fprintln(cmd.OutOrStdout(), "Operation cancelled. No prompt added.")
+		return nil
+	}
+	if _, err := app.SavePrompt("", form.fields()); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Prompt added successfully!")
+	return nil
+}
+
+// editWithForm edits every field of a prompt through the TUI form. A non-nil tags
+// overrides the stored tags. With review set, changes are shown as a diff to confirm first.
+func editWithForm(cmd *cobra.Command, app *prompts.App, editor tuiEditor, name string, tags *string, review bool) error {
+	existing, err := app.GetPrompt(name)
+	if err != nil {
+		return err
+	}
+	saved := promptForm{Name: existing.Name, Tags: existing.Tags, Description: existing.Description, Body: existing.Prompt}
+	current := saved
+	if tags != nil {
+		current.Tags = *tags
+	}
+	reader := bufio.NewReader(cmd.InOrStdin())
+
+	var form promptForm
+	for {
+		var result editResult
+		form, result, err = editor.Form(current, formOptions{AllTags: getAllTags(app), Exists: promptExists(app, existing.Name)})
+		if err != nil {
+			return err
+		}
+		if result == editCancelled {
+			fmt.Fprintln(cmd.OutOrStdout(), "Operation cancelled. Prompt unchanged.")
+			return nil
+		}
+		if form.Body, err = app.NormalizeContent(form.Body); err != nil {
+			return err
+		}
+		if !formChanged(saved, form) {
+			fmt.Fprintln(cmd.OutOrStdout(), "No changes detected for prompt or tags.")
+			return nil
+		}
+		if !review {
+			break
+		}
+		printEditDiff(cmd.OutOrStdout(), saved, form)
+		choice, err := askReview(reader, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		if choice == reviewDiscard {
+			fmt.Fprintln(cmd.OutOrStdout(), "Edit discarded. Prompt unchanged.")
+			return nil
+		}
+		if choice == reviewSave {
+			break
+		}
+		current = form
+	}
+
+	if _, err := app.SavePrompt(existing.Name, form.fields()); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Prompt edited successfully!")
+	return nil
+}
+
+func newGetCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get [name]",
+		Short: "Show a prompt",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := app.GetPrompt(args[0])
+			if err != nil {
+				return err
+			}
+			recordUsage(cmd, app, p.Name, prompts.UsageGet)
+			if raw, _ := cmd.Flags().GetBool("raw"); raw {
+				// Write the body exactly as stored, without a trailing newline of our own
+				_, err := io.WriteString(cmd.OutOrStdout(), p.Prompt)
+				return err
+			}
+			printPrompt(cmd.OutOrStdout(), *p)
+			return nil
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return completePromptName(app, toComplete)
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().Bool("raw", false, "Print only the prompt body, byte-for-byte as saved")
+	return cmd
+}
+
+// recordUsage logs a use of a prompt for frecency ranking. Failing to record
+// one should not fail the command, so errors are only reported as warnings.
+func recordUsage(cmd *cobra.Command, app *prompts.App, name string, kind prompts.UsageKind) {
+	if err := app.RecordUsage(name, kind); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", err)
+	}
+}
+
 // Uses go-fuzzyfinder for enhanced UX in interactive prompt search; stdlib filtering could suffice for simpler needs.
-func newSearchCmd(app *App) *cobra.Command {
-	return &cobra.Command{
+func newSearchCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "search",
 		Short: "Search for prompts using a fuzzy finder",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			prompts, err := app.ListPrompts("")
+			multi, _ := cmd.Flags().GetBool("multi")
+			query, _ := cmd.Flags().GetString("query")
+			list, err := app.FilterPrompts(prompts.PromptFilter{Query: query})
 			if err != nil {
 				return err
 			}
+			if len(list) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No prompts found")
+				return nil
+			}
+			if err := app.RankPrompts(list); err != nil {
+				return err
+			}
 
-			idx, err := fuzzyfinder.Find(
-				prompts,
-				func(i int) string {
-					return prompts[i].Name
-				},
-				fuzzyfinder.WithPreviewWindow(func(i, w, h int) string {
-					if i == -1 {
-						return ""
-					}
-					return fmt.Sprintf("Name: %s\nPrompt: %s\nTags: %s", prompts[i].Name, prompts[i].Prompt, prompts[i].Tags)
-				}),
-			)
+			itemFunc := func(i int) string {
+				return list[i].Name
+			}
+			preview := fuzzyfinder.WithPreviewWindow(func(i, w, h int) string {
+				if i == -1 {
+					return ""
+				}
+				return fmt.Sprintf("Name: %s\nPrompt: %s\nTags: %s", list[i].Name, list[i].Prompt, list[i].Tags)
+			})
+
+			if multi {
+				// Print bare names so the selection can be piped into export --names -
+				idxs, err := fuzzyfinder.FindMulti(list, itemFunc, preview)
+				if err != nil {
+					return fmt.Errorf("error finding prompts: %w", err)
+				}
+				for _, idx := range idxs {
+					fmt.Fprintln(cmd.OutOrStdout(), list[idx].Name)
+				}
+				return nil
+			}
+
+			idx, err := fuzzyfinder.Find(list, itemFunc, preview)
 			if err != nil {
 				return fmt.Errorf("error finding prompt: %w", err)
 			}
-			printPrompt(prompts[idx])
+			recordUsage(cmd, app, list[idx].Name, prompts.UsageGet)
+			printPrompt(cmd.OutOrStdout(), list[idx])
 			return nil
 		},
 	}
+	cmd.Flags().BoolP("multi", "m", false, "Select several prompts with Tab and print their names, one per line")
+	cmd.Flags().StringP("query", "q", "", `Only offer prompts matching a query, e.g. 'tag:go AND (name:review* OR body:"unit test") NOT tag:draft'`)
+	return cmd
 }
 
-func newDeleteCmd(app *App) *cobra.Command {
+func newDeleteCmd(app *prompts.App) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "delete [name]",
 		Short: "Delete a prompt",
@@ -314,12 +455,12 @@ func newDeleteCmd(app *App) *cobra.Command {
 			if err := app.DeletePrompt(name); err != nil {
 				return err
 			}
-			fmt.Println("Prompt deleted successfully!")
+			fmt.Fprintln(cmd.OutOrStdout(), "Prompt deleted successfully!")
 			return nil
 		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) == 0 {
-				return getPromptNames(app), cobra.ShellCompDirectiveNoFileComp
+				return completePromptName(app, toComplete)
 			}
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		},
@@ -327,57 +468,131 @@ func newDeleteCmd(app *App) *cobra.Command {
 	return cmd
 }
 
-func newEditCmd(app *App) *cobra.Command {
+// newPinCmd returns the pin command, or unpin when pinned is false.
+func newPinCmd(app *prompts.App, pinned bool) *cobra.Command {
+	use, short, done := "pin [name]", "Pin a prompt so it is offered first in search and completion", "Pinned"
+	if !pinned {
+		use, short, done = "unpin [name]", "Unpin a prompt", "Unpinned"
+	}
 	cmd := &cobra.Command{
-		Use:   "edit [name]",
-		Short: "Edit a prompt",
+		Use:   use,
+		Short: short,
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			name := args[0]
-			useExternalEditor, err := cmd.Flags().GetBool("external-editor")
-			if err != nil {
-				return fmt.Errorf("could not parse external-editor flag: %w", err)
+			if err := app.SetPinned(args[0], pinned); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s '%s'\n", done, args[0])
+			return nil
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return completePromptName(app, toComplete)
 			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
 
-			existingPrompt, err := app.promptStore.GetPromptByName(name)
+func newMoveCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mv [from] [to]",
+		Short: "Rename a prompt, or move a whole namespace such as code/ to eng/code/",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			moves, err := app.MovePrompts(args[0], args[1])
 			if err != nil {
 				return err
 			}
+			for _, m := range moves {
+				fmt.Fprintf(cmd.OutOrStdout(), "Moved %s -> %s\n", m.From, m.To)
+			}
+			return nil
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) < 2 {
+				return completePromptName(app, toComplete)
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
 
-			finalTags := existingPrompt.Tags
+func newEditCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit [name]",
+		Short: "Edit a prompt",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			var tags *string
 			if cmd.Flags().Changed("tags") {
-				finalTags, err = cmd.Flags().GetString("tags")
+				newTags, err := cmd.Flags().GetString("tags")
 				if err != nil {
 					return fmt.Errorf("could not parse tags flag: %w", err)
 				}
+				tags = &newTags
 			}
 
-			var editedPromptContent string
-			if useExternalEditor {
-				fmt.Println("Launching external editor...")
-				editedPromptContent, err = LaunchExternalEditor(existingPrompt.Prompt)
-			} else {
-				editedPromptContent, err = RunTUIEditor(existingPrompt.Prompt)
-			}
+			provider, err := contentProvider(cmd, app)
 			if err != nil {
 				return err
 			}
+			appendContent, _ := cmd.Flags().GetBool("append")
+			prependContent, _ := cmd.Flags().GetBool("prepend")
+			noReview, _ := cmd.Flags().GetBool("no-review")
+			switch editor := provider.(type) {
+			case tuiEditor:
+				if !appendContent && !prependContent {
+					return editWithForm(cmd, app, editor, name, tags, !noReview)
+				}
+			case externalEditor:
+				if !appendContent && !prependContent && !noReview {
+					existing, err := app.GetPrompt(name)
+					if err != nil {
+						return err
+					}
+					newTags := existing.Tags
+					if tags != nil {
+						newTags = *tags
+					}
+					provider = reviewedContent(cmd, app, editor, existing, newTags)
+				}
+			}
+			if appendContent || prependContent {
+				provider = extendContent(provider, prependContent)
+			}
 
-			if err := app.EditPrompt(existingPrompt, editedPromptContent, finalTags); err != nil {
+			result, err := app.EditPromptWith(name, tags, provider)
+			if errors.Is(err, prompts.ErrCancelled) {
+				fmt.Fprintln(cmd.OutOrStdout(), "Operation cancelled. Prompt unchanged.")
+				return nil
+			}
+			if err != nil {
 				return err
 			}
-			fmt.Println("Prompt edited successfully!")
+			if !result.Changed {
+				fmt.Fprintln(cmd.OutOrStdout(), "No changes detected for prompt or tags.")
+				return nil
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Prompt edited successfully!")
 			return nil
 		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) == 0 {
-				return getPromptNames(app), cobra.ShellCompDirectiveNoFileComp
+				return completePromptName(app, toComplete)
 			}
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		},
 	}
 	cmd.Flags().StringP("tags", "t", "", "New tags for the prompt (comma-separated)")
-	addExternalEditorFlag(cmd)
+	addContentFlags(cmd)
+	cmd.Flags().Bool("append", false, "Add the new content after the current content instead of replacing it")
+	cmd.Flags().Bool("prepend", false, "Add the new content before the current content instead of replacing it")
+	cmd.MarkFlagsMutuallyExclusive("append", "prepend")
+	cmd.Flags().Bool("no-review", false, "Save editor changes without showing a diff to confirm")
 
 	// Add completion for tags flag
 	_ = cmd.RegisterFlagCompletionFunc("tags", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -387,32 +602,79 @@ func newEditCmd(app *App) *cobra.Command {
 	return cmd
 }
 
-func newListCmd(app *App) *cobra.Command {
+func newListCmd(app *prompts.App) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "list",
-		Short: "List all prompts",
+		Use:   "list [namespace]",
+		Short: "List all prompts, or those under a namespace such as code/",
+		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			tags, _ := cmd.Flags().GetString("tags")
-			if tags == "" {
-				fmt.Println("No tags specified, listing all prompts")
+			query, _ := cmd.Flags().GetString("query")
+			pinned, _ := cmd.Flags().GetBool("pinned")
+			tree, _ := cmd.Flags().GetBool("tree")
+			namespace := ""
+			if len(args) == 1 {
+				namespace = strings.TrimSuffix(args[0], prompts.NamespaceSep) + prompts.NamespaceSep
+			}
+			if tags == "" && query == "" && !pinned && namespace == "" && !tree {
+				fmt.Fprintln(cmd.OutOrStdout(), "No tags specified, listing all prompts")
 			}
-			prompts, err := app.ListPrompts(tags)
+			list, err := app.FilterPrompts(prompts.PromptFilter{Tags: tags, Query: query, Pinned: pinned, Namespace: namespace})
 			if err != nil {
 				return err
 			}
+			if err := sortPrompts(cmd, app, list); err != nil {
+				return err
+			}
 
-			if len(prompts) == 0 && tags != "" {
-				fmt.Printf("No prompts found for tags: %s\n", tags)
+			if len(list) == 0 && namespace != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "No prompts found under %s\n", namespace)
 				return nil
 			}
 
-			for _, p := range prompts {
-				printPrompt(p)
+			if len(list) == 0 && query != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "No prompts found for query: %s\n", query)
+				return nil
+			}
+			if len(list) == 0 && tags != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "No prompts found for tags: %s\n", tags)
+				return nil
+			}
+			if len(list) == 0 && pinned {
+				fmt.Fprintln(cmd.OutOrStdout(), "No pinned prompts")
+				return nil
+			}
+
+			if tree {
+				printNameTree(cmd.OutOrStdout(), prompts.NameTree(list), "")
+				return nil
+			}
+			for _, p := range list {
+				printPrompt(cmd.OutOrStdout(), p)
 			}
 			return nil
 		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			var namespaces []string
+			for _, c := range prompts.CompleteNameSegment(getPromptNames(app), toComplete) {
+				if prompts.IsNamespace(c) {
+					namespaces = append(namespaces, c)
+				}
+			}
+			return namespaces, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveKeepOrder
+		},
 	}
+	cmd.Flags().Bool("tree", false, "Show only names, as a tree of namespaces")
 	cmd.Flags().StringP("tags", "t", "", "Filter by tags (comma-separated). Use AND:tag1,tag2 for AND logic, tag1,tag2 for OR logic")
+	cmd.Flags().StringP("query", "q", "", `Filter by a query, e.g. 'tag:go AND (name:review* OR body:"unit test") NOT tag:draft updated:>2026-01-01'`)
+	cmd.Flags().Bool("pinned", false, "List only pinned prompts")
+	cmd.Flags().String("sort", "name", "Order by: name, frecency (most used recently first), or updated (newest first)")
+	_ = cmd.RegisterFlagCompletionFunc("sort", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"name", "frecency", "updated"}, cobra.ShellCompDirectiveNoFileComp
+	})
 
 	// Add completion for tags flag
 	_ = cmd.RegisterFlagCompletionFunc("tags", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -422,95 +684,347 @@ func newListCmd(app *App) *cobra.Command {
 	return cmd
 }
 
-func newExportCmd(app *App) *cobra.Command {
-	return &cobra.Command{
+// printNameTree prints the children of node as an indented tree. Namespaces
+// end with a separator; a prompt that is also a namespace shows both.
+func printNameTree(w io.Writer, node *prompts.NameNode, indent string) {
+	for i, child := range node.Children {
+		branch, nextIndent := "├── ", indent+"│   "
+		if i == len(node.Children)-1 {
+			branch, nextIndent = "└── ", indent+"    "
+		}
+		label := child.Segment
+		switch {
+		case child.Prompt != nil && len(child.Children) > 0:
+			label += " (" + child.Segment + prompts.NamespaceSep + ")"
+		case len(child.Children) > 0:
+			label += prompts.NamespaceSep
+		}
+		fmt.Fprintln(w, indent+branch+label)
+		printNameTree(w, child, nextIndent)
+	}
+}
+
+// sortPrompts orders a listing by the --sort flag. Listings come sorted by name.
+func sortPrompts(cmd *cobra.Command, app *prompts.App, list []prompts.Prompt) error {
+	order, _ := cmd.Flags().GetString("sort")
+	switch order {
+	case "name":
+		return nil
+	case "frecency":
+		return app.SortByFrecency(list)
+	case "updated":
+		sort.SliceStable(list, func(i, j int) bool { return list[i].UpdatedAt.After(list[j].UpdatedAt) })
+		return nil
+	}
+	return prompts.Invalidf("--sort must be name, frecency, or updated")
+}
+
+func newRecentCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recent",
+		Short: "List the most recently used prompts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, _ := cmd.Flags().GetInt("limit")
+			used, err := app.RecentPrompts(limit)
+			if err != nil {
+				return err
+			}
+			printUsage(cmd.OutOrStdout(), used)
+			return nil
+		},
+	}
+	cmd.Flags().IntP("limit", "n", 10, "How many prompts to show (0 for all)")
+	return cmd
+}
+
+func newTopCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "List the prompts used most, weighted towards recent use",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, _ := cmd.Flags().GetInt("limit")
+			used, err := app.TopPrompts(limit)
+			if err != nil {
+				return err
+			}
+			printUsage(cmd.OutOrStdout(), used)
+			return nil
+		},
+	}
+	cmd.Flags().IntP("limit", "n", 10, "How many prompts to show (0 for all)")
+	return cmd
+}
+
+// printUsage prints one line per used prompt with its use count and when it was last used.
+func printUsage(w io.Writer, used []prompts.PromptUsage) {
+	if len(used) == 0 {
+		fmt.Fprintln(w, "No prompts used yet")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, u := range used {
+		fmt.Fprintf(tw, "%s\t%d uses\t%s\n", u.Name, u.Count, u.LastUsed.Local().Format("2006-01-02 15:04"))
+	}
+	tw.Flush()
+}
+
+func newExportCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "export [file]",
-		Short: "Export all prompts to a JSON file",
+		Short: "Export prompts to a file, directory, or stdout (-)",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			filename := args[0]
+			target := args[0]
+			formatFlag, _ := cmd.Flags().GetString("format")
+			includeIDs, _ := cmd.Flags().GetBool("with-ids")
 
-			prompts, err := app.ListPrompts("")
+			format, err := prompts.DetectFormat(target, formatFlag)
 			if err != nil {
-				return fmt.Errorf("error listing prompts: %w", err)
+				return err
 			}
 
-			if len(prompts) == 0 {
-				fmt.Println("No prompts to export")
-				return nil
+			filter, err := exportFilterFromFlags(cmd)
+			if err != nil {
+				return err
 			}
 
-			// Create JSON export
-			data, err := json.MarshalIndent(prompts, "", "  ")
+			list, err := app.FilterPrompts(filter)
 			if err != nil {
-				return fmt.Errorf("error marshaling prompts: %w", err)
+				return fmt.Errorf("error listing prompts: %w", err)
+			}
+
+			if len(list) == 0 {
+				fmt.Fprintln(cmd.ErrOrStderr(), "No prompts to export")
+				return nil
 			}
 
-			err = os.WriteFile(filename, data, 0o644)
+			switch {
+			case format == prompts.FormatMarkdown:
+				if target == "-" {
+					return fmt.Errorf("md format writes a directory and cannot be exported to stdout")
+				}
+				if !prompts.IsMarkdownFile(target) {
+					err = prompts.WriteMarkdownDir(target, list, includeIDs)
+				} else if len(list) == 1 {
+					err = prompts.WriteMarkdownFile(target, list[0], includeIDs)
+				} else {
+					return fmt.Errorf("a .md file holds one prompt; export %d prompts to a directory instead", len(list))
+				}
+			case target == "-":
+				err = prompts.WritePrompts(cmd.OutOrStdout(), format, list, includeIDs)
+			default:
+				err = writePromptsFile(target, format, list, includeIDs)
+			}
 			if err != nil {
-				return fmt.Errorf("error writing file: %w", err)
+				return err
 			}
 
-			fmt.Printf("Exported %d prompts to %s\n", len(prompts), filename)
+			if target != "-" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Exported %d prompts to %s\n", len(list), target)
+			}
 			return nil
 		},
 	}
+	addFormatFlag(cmd)
+	cmd.Flags().Bool("with-ids", false, "Include internal database IDs in the export")
+	cmd.Flags().StringP("tags", "t", "", "Export only prompts with these tags. Use AND:tag1,tag2 for AND logic, tag1,tag2 for OR logic")
+	cmd.Flags().String("names", "", "Export only these prompts (comma-separated, or - to read one name per line from stdin)")
+	cmd.Flags().String("since", "", "Export only prompts updated since a date (2006-01-02) or duration (7d, 36h)")
+	cmd.Flags().StringP("query", "q", "", `Export only prompts matching a query, e.g. 'tag:go NOT tag:draft', or whose name or content contains a word`)
+
+	_ = cmd.RegisterFlagCompletionFunc("tags", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getAllTags(app), cobra.ShellCompDirectiveNoFileComp
+	})
+	_ = cmd.RegisterFlagCompletionFunc("names", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getPromptNames(app), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	})
+
+	return cmd
+}
+
+// exportFilterFromFlags builds a PromptFilter from the export command's filter flags.
+func exportFilterFromFlags(cmd *cobra.Command) (prompts.PromptFilter, error) {
+	var filter prompts.PromptFilter
+	filter.Tags, _ = cmd.Flags().GetString("tags")
+	filter.Query, _ = cmd.Flags().GetString("query")
+
+	if names, _ := cmd.Flags().GetString("names"); names != "" {
+		parsed, err := parseNames(names, cmd.InOrStdin())
+		if err != nil {
+			return filter, err
+		}
+		filter.Names = parsed
+	}
+	if since, _ := cmd.Flags().GetString("since"); since != "" {
+		parsed, err := prompts.ParseSince(since, time.Now())
+		if err != nil {
+			return filter, err
+		}
+		filter.Since = parsed
+	}
+	return filter, nil
 }
 
-func newImportCmd(app *App) *cobra.Command {
-	return &cobra.Command{
+// addFormatFlag adds the format flag shared by export and import.
+func addFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("format", "f", "", "File format: json, jsonl, yaml, md, or csv (default: detected from the path)")
+	_ = cmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return prompts.Formats, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// writePromptsFile writes prompts to a file in a single-stream format.
+func writePromptsFile(filename, format string, list []prompts.Prompt, includeIDs bool) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if err := prompts.WritePrompts(f, format, list, includeIDs); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	return nil
+}
+
+// loadPrompts reads prompts from a file, a Markdown directory, or stdin (-).
+func loadPrompts(source, format string, stdin io.Reader) ([]prompts.Prompt, error) {
+	if format == prompts.FormatMarkdown {
+		return prompts.ReadMarkdown(source)
+	}
+	if source == "-" {
+		return prompts.ReadPrompts(stdin, format)
+	}
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	defer f.Close()
+	return prompts.ReadPrompts(f, format)
+}
+
+func newImportCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "import [file]",
-		Short: "Import prompts from a JSON file",
+		Short: "Import prompts from a file, directory, or stdin (-)",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			filename := args[0]
+			source := args[0]
+			formatFlag, _ := cmd.Flags().GetString("format")
+			from, _ := cmd.Flags().GetString("from")
+			onConflict, _ := cmd.Flags().GetString("on-conflict")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			strict, _ := cmd.Flags().GetBool("strict")
+
+			policy, err := prompts.ParseConflictPolicy(onConflict)
+			if err != nil {
+				return err
+			}
 
-			data, err := os.ReadFile(filename)
+			var list []prompts.Prompt
+			if from != "" {
+				list, err = prompts.ReadSource(from, source)
+			} else {
+				var format string
+				if format, err = prompts.DetectFormat(source, formatFlag); err == nil {
+					list, err = loadPrompts(source, format, cmd.InOrStdin())
+				}
+			}
 			if err != nil {
-				return fmt.Errorf("error reading file: %w", err)
+				return err
 			}
 
-			var prompts []Prompt
-			err = json.Unmarshal(data, &prompts)
+			reader := bufio.NewReader(cmd.InOrStdin())
+			plan, err := app.ImportPrompts(list, prompts.ImportOptions{
+				OnConflict: policy,
+				DryRun:     dryRun,
+				Strict:     strict,
+				Resolve: func(existing *prompts.Prompt, incoming prompts.Prompt) (prompts.ConflictPolicy, error) {
+					return askConflictPolicy(reader, cmd.OutOrStdout(), existing, incoming)
+				},
+			})
 			if err != nil {
-				return fmt.Errorf("error unmarshaling prompts: %w", err)
+				return err
 			}
 
-			imported := 0
-			skipped := 0
-			for _, prompt := range prompts {
-				// Skip prompts with empty names or content
-				if prompt.Name == "" || prompt.Prompt == "" {
-					skipped++
-					continue
+			for _, entry := range plan {
+				if entry.Status == prompts.ImportInvalid {
+					fmt.Fprintf(cmd.OutOrStdout(), "Rejected record %d ('%s'): %s\n", entry.Index, entry.Name, entry.Reason)
 				}
+			}
 
-				err := app.promptStore.AddPrompt(prompt.Name, prompt.Prompt, prompt.Tags)
-				if err != nil {
-					// If prompt already exists, try to update it
-					if strings.Contains(err.Error(), "already exists") {
-						err = app.promptStore.UpdatePrompt(prompt.Name, prompt.Prompt, prompt.Tags)
-						if err != nil {
-							fmt.Printf("Warning: failed to update prompt '%s': %v\n", prompt.Name, err)
-							skipped++
-							continue
-						}
-					} else {
-						fmt.Printf("Warning: failed to import prompt '%s': %v\n", prompt.Name, err)
-						skipped++
-						continue
-					}
+			if dryRun {
+				for _, entry := range plan {
+					fmt.Fprintf(cmd.OutOrStdout(), "%-10s %s -> %s\n", entry.Status, entry.Name, entry.Action)
 				}
-				imported++
+				return nil
 			}
 
-			fmt.Printf("Imported %d prompts, skipped %d\n", imported, skipped)
+			imported := 0
+			skipped := 0
+			for _, entry := range plan {
+				if entry.Skipped() {
+					skipped++
+				} else {
+					imported++
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Imported %d prompts, skipped %d\n", imported, skipped)
 			return nil
 		},
 	}
+	addFormatFlag(cmd)
+	cmd.Flags().String("from", "", "Read an external collection instead: awesome-chatgpt, fabric, or chatml")
+	cmd.Flags().String("on-conflict", string(prompts.ConflictSkip), "What to do when a prompt already exists: skip, overwrite, rename, merge-tags, or prompt")
+	cmd.Flags().Bool("dry-run", false, "Print what would be imported without changing the database")
+	cmd.Flags().Bool("strict", false, "Abort the import on the first invalid record")
+
+	_ = cmd.RegisterFlagCompletionFunc("on-conflict", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names := make([]string, len(prompts.ConflictPolicies))
+		for i, policy := range prompts.ConflictPolicies {
+			names[i] = string(policy)
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	})
+	_ = cmd.RegisterFlagCompletionFunc("from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return prompts.Sources, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+// askConflictPolicy asks the user how to resolve a single import conflict.
+func askConflictPolicy(reader *bufio.Reader, w io.Writer, existing *prompts.Prompt, incoming prompts.Prompt) (prompts.ConflictPolicy, error) {
+	fmt.Fprintf(w, "Prompt '%s' already exists.\n", existing.Name)
+	fmt.Fprintf(w, "  Current tags:  %s\n", existing.Tags)
+	fmt.Fprintf(w, "  Incoming tags: %s\n", incoming.Tags)
+	if existing.Prompt != incoming.Prompt {
+		fmt.Fprintln(w, "  Content differs.")
+	}
+	for {
+		fmt.Fprint(w, "[s]kip, [o]verwrite, [r]ename, [m]erge tags? ")
+		answer, err := reader.ReadString('\n')
+		if err != nil && answer == "" {
+			return "", fmt.Errorf("error reading answer: %w", err)
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "s", "skip":
+			return prompts.ConflictSkip, nil
+		case "o", "overwrite":
+			return prompts.ConflictOverwrite, nil
+		case "r", "rename":
+			return prompts.ConflictRename, nil
+		case "m", "merge", "merge-tags":
+			return prompts.ConflictMergeTags, nil
+		}
+	}
 }
 
-func newBackupCmd(app *App) *cobra.Command {
+func newBackupCmd(app *prompts.App) *cobra.Command {
 	return &cobra.Command{
 		Use:   "backup [file]",
 		Short: "Backup the database to a file",
@@ -518,70 +1032,323 @@ func newBackupCmd(app *App) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			backupPath := args[0]
 
-			// Copy the database file
-			sourceFile, err := os.Open(app.dbPath)
-			if err != nil {
-				return fmt.Errorf("error opening database file: %w", err)
-			}
-			defer sourceFile.Close()
-
-			destFile, err := os.Create(backupPath)
-			if err != nil {
-				return fmt.Errorf("error creating backup file: %w", err)
-			}
-			defer destFile.Close()
-
-			_, err = destFile.ReadFrom(sourceFile)
-			if err != nil {
-				return fmt.Errorf("error copying database: %w", err)
+			if err := app.BackupDatabase(backupPath); err != nil {
+				return err
 			}
 
-			fmt.Printf("Database backed up to %s\n", backupPath)
+			fmt.Fprintf(cmd.OutOrStdout(), "Database backed up to %s\n", backupPath)
 			return nil
 		},
 	}
 }
 
-func newRestoreCmd(app *App) *cobra.Command {
-	return &cobra.Command{
+func newRestoreCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "restore [file]",
 		Short: "Restore the database from a backup file",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			backupPath := args[0]
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-			// Check if backup file exists
 			if _, err := os.Stat(backupPath); os.IsNotExist(err) {
 				return fmt.Errorf("backup file does not exist: %s", backupPath)
 			}
 
-			// Close the current database connection
-			// Note: This is a limitation - we can't close the DB from within the app
-			// In a real implementation, you'd want to handle this differently
+			if dryRun {
+				backupInfo, err := prompts.VerifyBackup(backupPath)
+				if err != nil {
+					return err
+				}
+				currentInfo, err := prompts.InspectDatabase(app.DBPath())
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Backup:  %d prompts (schema version %d)\n", backupInfo.PromptCount, backupInfo.SchemaVersion)
+				fmt.Fprintf(cmd.OutOrStdout(), "Current: %d prompts (schema version %d)\n", currentInfo.PromptCount, currentInfo.SchemaVersion)
+				return nil
+			}
+
+			snapshotPath, err := app.RestoreDatabase(backupPath)
+			if err != nil {
+				return err
+			}
 
-			// Copy the backup file to the database location
-			sourceFile, err := os.Open(backupPath)
+			fmt.Fprintf(cmd.OutOrStdout(), "Database restored from %s\n", backupPath)
+			fmt.Fprintf(cmd.OutOrStdout(), "Previous database saved to %s\n", snapshotPath)
+			return nil
+		},
+	}
+	cmd.Flags().Bool("dry-run", false, "Show prompt counts in the backup and current database without restoring")
+	return cmd
+}
+
+func newSyncCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync the prompt library with a git repository",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := app.SyncGit()
 			if err != nil {
-				return fmt.Errorf("error opening backup file: %w", err)
+				return err
 			}
-			defer sourceFile.Close()
 
-			destFile, err := os.Create(app.dbPath)
+			if result.Commit != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Committed: %s\n", result.Commit)
+			}
+			if len(result.Conflicts) > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "Sync stopped on conflicts:")
+				for _, name := range result.Conflicts {
+					fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", name)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "Resolve each with 'p sync resolve <name> --keep local|remote' (or edit the file and git add it), then run 'p sync' again.")
+				return fmt.Errorf("%d unresolved sync conflicts", len(result.Conflicts))
+			}
+			printSyncChanges(cmd.OutOrStdout(), result.Imported)
+			if result.Pushed {
+				fmt.Fprintln(cmd.OutOrStdout(), "Pushed to remote")
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Sync complete")
+			return nil
+		},
+	}
+	cmd.AddCommand(newSyncInitCmd(app), newSyncResolveCmd(app), newSyncDirCmd(app))
+	return cmd
+}
+
+func newSyncInitCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init [dir]",
+		Short: "Set up a git repository for syncing, optionally cloned from a remote",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			remote, _ := cmd.Flags().GetString("remote")
+			onConflict, _ := cmd.Flags().GetString("on-conflict")
+			policy, err := prompts.ParseConflictPolicy(onConflict)
 			if err != nil {
-				return fmt.Errorf("error creating database file: %w", err)
+				return err
 			}
-			defer destFile.Close()
 
-			_, err = destFile.ReadFrom(sourceFile)
+			reader := bufio.NewReader(cmd.InOrStdin())
+			plan, err := app.InitGitSync(args[0], remote, prompts.ImportOptions{
+				OnConflict: policy,
+				Resolve: func(existing *prompts.Prompt, incoming prompts.Prompt) (prompts.ConflictPolicy, error) {
+					return askConflictPolicy(reader, cmd.OutOrStdout(), existing, incoming)
+				},
+			})
 			if err != nil {
-				return fmt.Errorf("error restoring database: %w", err)
+				return err
 			}
 
-			fmt.Printf("Database restored from %s\n", backupPath)
-			fmt.Println("Note: You may need to restart the application for changes to take effect")
+			for _, entry := range plan {
+				if entry.Status == prompts.ImportConflict || entry.Status == prompts.ImportInvalid {
+					fmt.Fprintf(cmd.OutOrStdout(), "%-10s %s -> %s\n", entry.Status, entry.Name, entry.Action)
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Sync repository ready at %s. Run 'p sync' to sync.\n", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().String("remote", "", "Git remote URL to clone or add as origin")
+	cmd.Flags().String("on-conflict", string(prompts.ConflictSkip), "How to treat prompts already in the repository: skip, overwrite, rename, merge-tags, or prompt")
+	return cmd
+}
+
+func newSyncResolveCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resolve [name]",
+		Short: "Resolve a sync conflict by keeping the local or remote version",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keep, _ := cmd.Flags().GetString("keep")
+			if keep != "local" && keep != "remote" {
+				return prompts.Invalidf("--keep must be 'local' or 'remote'")
+			}
+			if err := app.ResolveGitConflict(args[0], keep == "remote"); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Resolved '%s' with the %s version\n", args[0], keep)
 			return nil
 		},
 	}
+	cmd.Flags().String("keep", "", "Which version to keep: local or remote")
+	_ = cmd.MarkFlagRequired("keep")
+	return cmd
+}
+
+func newSyncDirCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dir [path]",
+		Short: "Two-way sync the prompt library with a folder of Markdown files",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			prefer, _ := cmd.Flags().GetString("prefer")
+			result, err := app.SyncDir(args[0], prefer)
+			if err != nil {
+				return err
+			}
+
+			printSyncChanges(cmd.OutOrStdout(), result.ToLibrary)
+			for _, name := range result.ToFolder.Added {
+				fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", name)
+			}
+			for _, name := range result.ToFolder.Updated {
+				fmt.Fprintf(cmd.OutOrStdout(), "Rewrote %s\n", name)
+			}
+			for _, name := range result.ToFolder.Deleted {
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed file for %s\n", name)
+			}
+
+			if len(result.Conflicts) > 0 {
+				for _, c := range result.Conflicts {
+					printDirSyncConflict(cmd.OutOrStdout(), c)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "Edit one side, or rerun with --prefer library|folder|newer, then sync again.")
+				return fmt.Errorf("%d prompts changed on both sides", len(result.Conflicts))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Sync complete")
+			return nil
+		},
+	}
+	cmd.Flags().String("prefer", "", "Settle prompts changed on both sides by keeping: library, folder, or newer")
+	_ = cmd.RegisterFlagCompletionFunc("prefer", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{prompts.PreferLibrary, prompts.PreferFolder, prompts.PreferNewer}, cobra.ShellCompDirectiveNoFileComp
+	})
+	return cmd
+}
+
+// printDirSyncConflict prints the base, library, and folder versions of a conflicted prompt.
+func printDirSyncConflict(w io.Writer, c prompts.DirSyncConflict) {
+	fmt.Fprintf(w, "=== Conflict: %s ===\n", c.Name)
+	for _, version := range []struct{ label, content, missing string }{
+		{"base (last sync)", c.Base, "(not available)"},
+		{"library", c.Library, "(deleted)"},
+		{"folder", c.Folder, "(deleted)"},
+	} {
+		fmt.Fprintf(w, "--- %s ---\n", version.label)
+		if version.content == "" {
+			fmt.Fprintln(w, version.missing)
+		} else {
+			fmt.Fprintln(w, strings.TrimRight(version.content, "\n"))
+		}
+	}
+	fmt.Fprintln(w, "===")
+}
+
+// printSyncChanges prints the prompts a sync changed in the library.
+func printSyncChanges(w io.Writer, c prompts.SyncChanges) {
+	for _, name := range c.Added {
+		fmt.Fprintf(w, "Added %s\n", name)
+	}
+	for _, name := range c.Updated {
+		fmt.Fprintf(w, "Updated %s\n", name)
+	}
+	for _, name := range c.Deleted {
+		fmt.Fprintf(w, "Deleted %s\n", name)
+	}
+}
+
+func newServeCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the prompt library as a local HTTP/JSON API",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, _ := cmd.Flags().GetString("addr")
+			token, _ := cmd.Flags().GetString("token")
+			origins, _ := cmd.Flags().GetStringSlice("cors-origin")
+			if token == "" {
+				token = os.Getenv("P_SERVE_TOKEN")
+			}
+
+			handler := newServer(app, serverOptions{Addr: addr, Token: token, CORSOrigins: origins})
+			fmt.Fprintf(cmd.OutOrStdout(), "Serving prompts on http://%s/api/prompts\n", addr)
+			return http.ListenAndServe(addr, handler)
+		},
+	}
+	cmd.Flags().String("addr", "127.0.0.1:8377", "Address to listen on")
+	cmd.Flags().String("token", "", "Require this bearer token on every request (default: $P_SERVE_TOKEN)")
+	cmd.Flags().StringSlice("cors-origin", nil, "Allow browser requests from this origin (repeatable, * for any)")
+	return cmd
+}
+
+func newMCPCmd(app *prompts.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "mcp",
+		Short: "Run a Model Context Protocol server over stdio",
+		Long: `Run a Model Context Protocol server over stdio.
+
+Every stored prompt is published as an MCP prompt, with its {{variables}} as
+arguments and its tags in the description. The search_prompts, add_prompt and
+update_prompt tools let clients query and edit the library.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server := &mcpServer{app: app}
+			return server.serve(cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+}
+
+// configKeys are the settings p config can show and change.
+var configKeys = []string{"normalize"}
+
+func newConfigCmd(app *prompts.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config [key] [value]",
+		Short: "Show or change settings",
+		Long: `Show or change settings. With no arguments every setting is shown.
+
+Settings:
+  normalize  Whitespace cleanup applied when saving prompt content:
+             none (byte-exact, the default), trailing-newline (drop one final
+             newline), or full (trim leading and trailing whitespace)`,
+		Args: cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				n, err := app.Normalization()
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "normalize=%s\n", n)
+				return nil
+			}
+			if args[0] != "normalize" {
+				return fmt.Errorf("unknown setting '%s' (known: %s)", args[0], strings.Join(configKeys, ", "))
+			}
+			if len(args) == 1 {
+				n, err := app.Normalization()
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), n)
+				return nil
+			}
+			n, err := prompts.ParseNormalization(args[1])
+			if err != nil {
+				return err
+			}
+			if err := app.SetNormalization(n); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "normalize set to %s\n", n)
+			return nil
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return configKeys, cobra.ShellCompDirectiveNoFileComp
+			case 1:
+				var values []string
+				for _, n := range prompts.Normalizations {
+					values = append(values, string(n))
+				}
+				return values, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
 }
 
 func newVersionCmd() *cobra.Command {
@@ -589,7 +1356,7 @@ func newVersionCmd() *cobra.Command {
 		Use:   "version",
 		Short: "Print the version number of p",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("p version %s\n", Version)
+			fmt.Fprintf(cmd.OutOrStdout(), "p version %s\n", Version)
 		},
 	}
 }
@@ -1,312 +1,834 @@
 package main
 
 import (
-	"database/sql"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+	"github.com/yookibooki/p/prompts"
 )
 
-func TestValidatePromptName(t *testing.T) {
-	tests := []struct {
-		name    string
-		input   string
-		wantErr bool
-	}{
-		{"empty name", "", true},
-		{"valid name", "test", false},
-		{"max length name", string(make([]byte, 255)), false},
-		{"too long name", string(make([]byte, 256)), true},
+// setupTestApp opens a migrated database in a temporary directory.
+func setupTestApp(t *testing.T) *prompts.App {
+	dbPath := filepath.Join(t.TempDir(), "prompts.db")
+	db, err := prompts.OpenDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
 	}
+	t.Cleanup(func() { db.Close() })
+	return prompts.NewApp(prompts.NewSQLitePromptStore(db), dbPath)
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := validatePromptName(tt.input)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("validatePromptName() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
+func TestAddPromptIntegration(t *testing.T) {
+	app := setupTestApp(t)
+	fakeEditor(t, `echo "test content" > "$1"`)
+
+	// Test adding a prompt using external editor
+	var out bytes.Buffer
+	cmd := newAddCmd(app)
+	cmd.SetArgs([]string{"testprompt", "--tags", "test,cli", "--external-editor"})
+	cmd.SetOut(&out)
+	if err := cmd.Execute(); err != nil {
+		t.Errorf("add failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Prompt added successfully!") {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+
+	// Verify prompt was added
+	prompt, err := app.GetPrompt("testprompt")
+	if err != nil {
+		t.Errorf("Failed to retrieve added prompt: %v", err)
+	}
+	if prompt.Name != "testprompt" {
+		t.Errorf("Expected prompt name 'testprompt', got '%s'", prompt.Name)
 	}
 }
 
-func TestValidatePromptContent(t *testing.T) {
-	tests := []struct {
-		name    string
-		content string
-		wantErr bool
-	}{
-		{"empty content", "", true},
-		{"whitespace only", "   \n\t  ", true},
-		{"valid content", "test prompt", false},
-		{"max length content", string(make([]byte, 10000)), false},
-		{"too long content", string(make([]byte, 10001)), true},
+func TestParseNames(t *testing.T) {
+	names, err := parseNames("-", strings.NewReader("a\n\n b \nc\n"))
+	if err != nil || strings.Join(names, ",") != "a,b,c" {
+		t.Errorf("parseNames from stdin = %v, %v", names, err)
 	}
+	names, err = parseNames("a, b,,c", nil)
+	if err != nil || strings.Join(names, ",") != "a,b,c" {
+		t.Errorf("parseNames(list) = %v, %v", names, err)
+	}
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := validatePromptContent(tt.content)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("validatePromptContent() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
+func TestServerAPI(t *testing.T) {
+	app := setupTestApp(t)
+	handler := newServer(app, serverOptions{Addr: "127.0.0.1:8377", Token: "secret", CORSOrigins: []string{"https://ext.example"}})
+
+	send := func(req *http.Request, auth bool) *httptest.ResponseRecorder {
+		if auth {
+			req.Header.Set("Authorization", "Bearer secret")
+		}
+		req.Header.Set("Origin", "https://ext.example")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	do := func(method, path, body string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "http://127.0.0.1:8377"+path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return send(req, auth)
+	}
+
+	if rec := do("GET", "/api/prompts", "", false); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rec.Code)
+	}
+	if rec := do("OPTIONS", "/api/prompts", "", false); rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://ext.example" {
+		t.Errorf("Expected CORS preflight to succeed, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec := do("POST", "/api/prompts", `{"name":"review","prompt":"Review {{lang}} code","tags":["code","review"]}`, true)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Create failed: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("POST", "/api/prompts", `{"name":"review","prompt":"again"}`, true); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for duplicate, got %d", rec.Code)
+	}
+	if rec := do("POST", "/api/prompts", `{"name":"empty","prompt":"  "}`, true); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty content, got %d", rec.Code)
+	}
+	req := httptest.NewRequest("POST", "http://127.0.0.1:8377/api/prompts", strings.NewReader(`{"name":"form","prompt":"x"}`))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rec := send(req, true); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a body that is not JSON, got %d", rec.Code)
+	}
+	req = httptest.NewRequest("GET", "http://rebound.example:8377/api/prompts", nil)
+	if rec := send(req, true); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a foreign Host, got %d", rec.Code)
+	}
+	huge := `{"name":"huge","prompt":"` + strings.Repeat("x", maxBodyBytes) + `"}`
+	if rec := do("POST", "/api/prompts", huge, true); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a body over the limit, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do("GET", "/api/prompts/review", "", true)
+	var got apiPrompt
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.Variables[0] != "lang" || len(got.Tags) != 2 {
+		t.Errorf("Unexpected prompt: %s", rec.Body)
+	}
+
+	rec = do("PUT", "/api/prompts/review", `{"tags":["go"]}`, true)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"tags":["go"]`) {
+		t.Errorf("Update failed: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do("POST", "/api/render/review", `{"variables":{"lang":"Go"}}`, true)
+	if !strings.Contains(rec.Body.String(), `"rendered":"Review Go code"`) {
+		t.Errorf("Unexpected render: %s", rec.Body)
+	}
+
+	if rec := do("POST", "/api/usage/review", `{"kind":"copy"}`, true); rec.Code != http.StatusNoContent {
+		t.Errorf("Usage failed: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("POST", "/api/usage/review", `{"kind":"print"}`, true); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown usage kind, got %d", rec.Code)
+	}
+	// One get, one render and one copy
+	if used, err := app.TopPrompts(0); err != nil || len(used) != 1 || used[0].Count != 3 {
+		t.Errorf("TopPrompts() = %+v, %v", used, err)
+	}
+
+	rec = do("GET", "/api/tags", "", true)
+	if !strings.Contains(rec.Body.String(), `{"name":"go","count":1}`) {
+		t.Errorf("Unexpected tags: %s", rec.Body)
+	}
+
+	if rec := do("DELETE", "/api/prompts/review", "", true); rec.Code != http.StatusNoContent {
+		t.Errorf("Delete failed: %d", rec.Code)
+	}
+	if rec := do("GET", "/api/prompts/review", "", true); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", rec.Code)
 	}
 }
 
-func TestNormalizeTags(t *testing.T) {
+func TestAllowedHost(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    string
-		expected string
+		host, addr string
+		want       bool
 	}{
-		{"empty tags", "", ""},
-		{"single tag", "test", "test"},
-		{"multiple tags", "b,a,c", "a,b,c"},
-		{"duplicate tags", "test,test,other", "other,test"},
-		{"whitespace tags", " a , b , c ", "a,b,c"},
-		{"malformed tags", "a,,b,,,c", "a,b,c"},
+		{"127.0.0.1:8377", "127.0.0.1:8377", true},
+		{"localhost:8377", "127.0.0.1:8377", true},
+		{"[::1]:8377", "127.0.0.1:8377", true},
+		{"127.0.0.2", "127.0.0.1:8377", true},
+		{"prompts.lan:8377", "prompts.lan:8377", true},
+		{"prompts.lan", "prompts.lan:8377", true},
+		{"rebound.example:8377", "127.0.0.1:8377", false},
+		{"rebound.example:8377", ":8377", false},
+		{"0.0.0.0:8377", "0.0.0.0:8377", true},
+		{"rebound.example:8377", "0.0.0.0:8377", false},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := normalizeTags(tt.input)
-			if result != tt.expected {
-				t.Errorf("normalizeTags() = %v, want %v", result, tt.expected)
-			}
-		})
+		if got := allowedHost(tt.host, tt.addr); got != tt.want {
+			t.Errorf("allowedHost(%q, %q) = %v, want %v", tt.host, tt.addr, got, tt.want)
+		}
 	}
 }
 
-func setupTestDB(t *testing.T) (*SQLitePromptStore, string) {
-	tmpFile, err := os.CreateTemp("", "test_*.db")
-	if err != nil {
+func TestMCPServer(t *testing.T) {
+	app := setupTestApp(t)
+	if _, err := app.CreatePrompt("review", "Review this {{lang}} code", "code,review"); err != nil {
 		t.Fatal(err)
 	}
-	tmpFile.Close()
-	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
-
-	db, err := sql.Open("sqlite3", tmpFile.Name())
-	if err != nil {
+	if err := app.SetDescription("review", "Reviews code"); err != nil {
 		t.Fatal(err)
 	}
 
-	sqlStmt := `CREATE TABLE IF NOT EXISTS prompts (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL UNIQUE,
-		prompt TEXT NOT NULL,
-		tags TEXT
-	);`
+	requests := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"prompts/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"prompts/get","params":{"name":"review","arguments":{"lang":"Go"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"prompts/get","params":{"name":"review"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"add_prompt","arguments":{"name":"summary","prompt":"Summarize","tags":"text"}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"search_prompts","arguments":{"query":"summar"}}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"bogus"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	server := &mcpServer{app: app}
+	if err := server.serve(strings.NewReader(requests), &out); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("Expected 7 responses (no reply to the notification), got %d:\n%s", len(lines), out.String())
+	}
+	checks := []string{
+		`"protocolVersion":"2025-06-18"`,
+		`"arguments":[{"name":"lang","required":true}],"description":"Reviews code (Tags: code, review)"`,
+		`"text":"Review this Go code"`,
+		`"code":-32602`,
+		`Added prompt 'summary'`,
+		`\"name\": \"summary\"`,
+		`"code":-32601`,
+	}
+	for i, want := range checks {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("Response %d missing %s:\n%s", i+1, want, lines[i])
+		}
+	}
+}
 
-	if _, err = db.Exec(sqlStmt); err != nil {
-		t.Fatal(err)
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("boom"), exitError},
+		{fmt.Errorf("prompt 'x' %w", prompts.ErrNotFound), exitNotFound},
+		{fmt.Errorf("prompt name 'x' %w", prompts.ErrDuplicate), exitDuplicate},
+		{prompts.ValidatePromptName(""), exitInvalid},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
 	}
+	_, err := runCmd(newSyncResolveCmd(setupTestApp(t)), "", "review", "--keep", "mine")
+	if got := exitCode(err); got != exitInvalid {
+		t.Errorf("sync resolve --keep mine exits %d, want %d", got, exitInvalid)
+	}
+}
 
-	store := NewSQLitePromptStore(db)
-	t.Cleanup(func() { db.Close() })
-	return store, tmpFile.Name()
+// runCmd executes cmd with args and stdin, returning its output.
+func runCmd(cmd *cobra.Command, stdin string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd.SetArgs(args)
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	err := cmd.Execute()
+	return out.String(), err
 }
 
-func TestAppWithEmptyDB(t *testing.T) {
-	store, dbPath := setupTestDB(t)
-	app := NewApp(store, dbPath)
+func TestAddEditNonInteractive(t *testing.T) {
+	app := setupTestApp(t)
+	path := filepath.Join(t.TempDir(), "body.txt")
+	if err := os.WriteFile(path, []byte("from file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
 
-	// Test listing empty DB
-	prompts, err := app.ListPrompts("")
-	if err != nil {
-		t.Errorf("ListPrompts() on empty DB failed: %v", err)
+	if _, err := runCmd(newAddCmd(app), "", "flag", "--content", "from flag", "-t", "x"); err != nil {
+		t.Fatalf("add --content failed: %v", err)
+	}
+	if _, err := runCmd(newAddCmd(app), "", "file", "--file", path); err != nil {
+		t.Fatalf("add --file failed: %v", err)
 	}
-	if len(prompts) != 0 {
-		t.Errorf("Expected 0 prompts, got %d", len(prompts))
+	if _, err := runCmd(newAddCmd(app), "from stdin\n", "piped", "-t", "x"); err != nil {
+		t.Fatalf("add from stdin failed: %v", err)
+	}
+	if _, err := runCmd(newAddCmd(app), "", "empty"); !errors.Is(err, prompts.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for empty stdin, got %v", err)
+	}
+	if _, err := runCmd(newAddCmd(app), "", "both", "--content", "a", "--file", path); err == nil {
+		t.Error("Expected error for --content with --file")
+	}
+
+	if _, err := runCmd(newEditCmd(app), "second line", "piped", "--append"); err != nil {
+		t.Fatalf("edit --append failed: %v", err)
+	}
+	if _, err := runCmd(newEditCmd(app), "", "piped", "--prepend", "--content", "first line"); err != nil {
+		t.Fatalf("edit --prepend failed: %v", err)
+	}
+	if _, err := runCmd(newEditCmd(app), "replaced", "file"); err != nil {
+		t.Fatalf("edit from stdin failed: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"flag":  "from flag",
+		"file":  "replaced",
+		"piped": "first line\nfrom stdin\n\nsecond line",
+	} {
+		p, err := app.GetPrompt(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Prompt != want {
+			t.Errorf("Prompt %s = %q, want %q", name, p.Prompt, want)
+		}
 	}
 }
 
-func TestDuplicateNames(t *testing.T) {
-	store, _ := setupTestDB(t)
+func TestGetRawAndConfig(t *testing.T) {
+	app := setupTestApp(t)
+	if out, err := runCmd(newConfigCmd(app), "", "normalize"); err != nil || out != "none\n" {
+		t.Errorf("config normalize = %q, %v", out, err)
+	}
+	if _, err := runCmd(newConfigCmd(app), "", "normalize", "strip"); !errors.Is(err, prompts.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for unknown policy, got %v", err)
+	}
+	if _, err := runCmd(newConfigCmd(app), "", "colour", "red"); err == nil {
+		t.Error("Expected error for unknown setting")
+	}
 
-	// Add first prompt
-	err := store.AddPrompt("test", "content1", "tag1")
-	if err != nil {
+	body := "```go\n\tfmt.Println()\n```\n\n"
+	if _, err := runCmd(newAddCmd(app), body, "exact"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCmd(newConfigCmd(app), "", "normalize", "trailing-newline"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCmd(newAddCmd(app), body, "trimmed"); err != nil {
 		t.Fatal(err)
 	}
 
-	// Try to add duplicate name - should fail due to UNIQUE constraint
-	err = store.AddPrompt("test", "content2", "tag2")
-	if err == nil {
-		t.Error("Expected error for duplicate name, got nil")
+	for name, want := range map[string]string{"trimmed": strings.TrimSuffix(body, "\n"), "exact": body} {
+		out, err := runCmd(newGetCmd(app), "", name, "--raw")
+		if err != nil || out != want {
+			t.Errorf("get %s --raw = %q, %v; want %q", name, out, err, want)
+		}
+	}
+	if out, _ := runCmd(newGetCmd(app), "", "exact"); !strings.Contains(out, "Name: exact") {
+		t.Errorf("get without --raw should show the prompt details, got %q", out)
 	}
 }
 
-func TestEditorCancellation(t *testing.T) {
-	// Test that validation catches empty content
-	err := validatePromptContent("")
-	if err == nil {
-		t.Error("Expected error for empty content, got nil")
+func TestListQuery(t *testing.T) {
+	app := setupTestApp(t)
+	for name, tags := range map[string]string{"go-review": "go", "go-draft": "go,draft"} {
+		if _, err := app.CreatePrompt(name, "body", tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := runCmd(newListCmd(app), "", "--query", "tag:go NOT tag:draft")
+	if err != nil || !strings.Contains(out, "Name: go-review") || strings.Contains(out, "go-draft") {
+		t.Errorf("list --query = %q, %v", out, err)
+	}
+	if _, err := runCmd(newListCmd(app), "", "-q", "tag:go AND"); !errors.Is(err, prompts.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for a bad query, got %v", err)
+	}
+	out, err = runCmd(newExportCmd(app), "", "-", "--format", "jsonl", "--query", "name:*draft")
+	if err != nil || strings.Count(out, "\n") != 1 || !strings.Contains(out, "go-draft") {
+		t.Errorf("export --query = %q, %v", out, err)
+	}
+}
+
+func TestUsageCommands(t *testing.T) {
+	app := setupTestApp(t)
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		if _, err := app.CreatePrompt(name, "body of "+name, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, _ := runCmd(newRecentCmd(app), ""); out != "No prompts used yet\n" {
+		t.Errorf("recent with no usage = %q", out)
+	}
+	for _, name := range []string{"gamma", "beta", "gamma"} {
+		if _, err := runCmd(newGetCmd(app), "", name, "--raw"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := runCmd(newTopCmd(app), "", "-n", "1")
+	if err != nil || !strings.HasPrefix(out, "gamma") || !strings.Contains(out, "2 uses") || strings.Contains(out, "beta") {
+		t.Errorf("top -n 1 = %q, %v", out, err)
 	}
+	out, err = runCmd(newListCmd(app), "", "--sort", "frecency")
+	if err != nil || strings.Index(out, "gamma") > strings.Index(out, "beta") || strings.Index(out, "beta") > strings.Index(out, "alpha") {
+		t.Errorf("list --sort frecency order wrong:\n%s", out)
+	}
+	if _, err := runCmd(newListCmd(app), "", "--sort", "size"); !errors.Is(err, prompts.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for unknown sort order, got %v", err)
+	}
+	if names := getPromptNames(app); strings.Join(names, ",") != "gamma,beta,alpha" {
+		t.Errorf("getPromptNames() = %v, want frecency order", names)
+	}
+}
 
-	// Test that whitespace-only content is properly validated
-	err = validatePromptContent("   \n\t  ")
-	if err == nil {
-		t.Error("Expected error for whitespace-only content, got nil")
+func TestPinCommands(t *testing.T) {
+	app := setupTestApp(t)
+	for _, name := range []string{"daily", "other"} {
+		if _, err := app.CreatePrompt(name, "body", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := runCmd(newListCmd(app), "", "--pinned"); err != nil || out != "No pinned prompts\n" {
+		t.Errorf("list --pinned with none pinned = %q, %v", out, err)
+	}
+	if _, err := runCmd(newPinCmd(app, true), "", "other"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := runCmd(newListCmd(app), "", "--pinned")
+	if err != nil || !strings.Contains(out, "Name: other") || !strings.Contains(out, "Pinned: yes") || strings.Contains(out, "daily") {
+		t.Errorf("list --pinned = %q, %v", out, err)
+	}
+	if names := getPromptNames(app); names[0] != "other" {
+		t.Errorf("getPromptNames() = %v, want the pinned prompt first", names)
+	}
+	if _, err := runCmd(newPinCmd(app, false), "", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCmd(newPinCmd(app, true), "", "missing"); !errors.Is(err, prompts.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound pinning a missing prompt, got %v", err)
 	}
 }
 
-func setupMockEditor(t *testing.T) {
-	mockScript := `#!/bin/bash
-echo "test content" > "$1"
+func TestNamespaceCommands(t *testing.T) {
+	app := setupTestApp(t)
+	for _, name := range []string{"code", "code/review/go", "code/tests", "writing/email"} {
+		if _, err := app.CreatePrompt(name, "body", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := runCmd(newListCmd(app), "", "code/review")
+	if err != nil || !strings.Contains(out, "Name: code/review/go") || strings.Contains(out, "code/tests") {
+		t.Errorf("list code/review = %q, %v", out, err)
+	}
+	want := `├── code (code/)
+│   ├── review/
+│   │   └── go
+│   └── tests
+└── writing/
+    └── email
 `
-	mockFile, err := os.CreateTemp("", "mock_editor_*.sh")
-	if err != nil {
-		t.Fatal(err)
+	if out, err := runCmd(newListCmd(app), "", "--tree"); err != nil || out != want {
+		t.Errorf("list --tree =\n%s, %v; want\n%s", out, err, want)
 	}
-	defer mockFile.Close()
 
-	if _, err := mockFile.WriteString(mockScript); err != nil {
-		t.Fatal(err)
+	out, err = runCmd(newMoveCmd(app), "", "code/", "eng/code/")
+	if err != nil || !strings.Contains(out, "Moved code/review/go -> eng/code/review/go") {
+		t.Errorf("mv code/ eng/code/ = %q, %v", out, err)
+	}
+	if out, _ := runCmd(newListCmd(app), "", "code/"); out != "No prompts found under code/\n" {
+		t.Errorf("list code/ after mv = %q", out)
 	}
 
-	if err := os.Chmod(mockFile.Name(), 0o755); err != nil {
-		t.Fatal(err)
+	completions, directive := completePromptName(app, "eng/")
+	if strings.Join(completions, ",") != "eng/code/" || directive&cobra.ShellCompDirectiveNoSpace == 0 {
+		t.Errorf("completePromptName(eng/) = %v, %v", completions, directive)
+	}
+	completions, directive = completePromptName(app, "eng/code/t")
+	if strings.Join(completions, ",") != "eng/code/tests" || directive&cobra.ShellCompDirectiveNoSpace != 0 {
+		t.Errorf("completePromptName(eng/code/t) = %v, %v", completions, directive)
 	}
+}
 
-	os.Setenv("EDITOR", mockFile.Name())
-	t.Cleanup(func() {
-		os.Remove(mockFile.Name())
-		os.Unsetenv("EDITOR")
-	})
+func TestTagCompletion(t *testing.T) {
+	all := []string{"code", "coding", "review", "writing"}
+	tests := []struct {
+		value, want string
+	}{
+		{"", ""},
+		{"co", "de"},
+		{"cod", "e"},
+		{"code, re", "view"},
+		{"coding, co", "de"},
+		{"code,cod", "ing"},
+		{"xyz", ""},
+	}
+	for _, tt := range tests {
+		if got := tagCompletion(tt.value, all); got != tt.want {
+			t.Errorf("tagCompletion(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
 }
 
-func TestAddPromptIntegration(t *testing.T) {
-	store, dbPath := setupTestDB(t)
-	app := NewApp(store, dbPath)
-	setupMockEditor(t)
+func TestEditorForm(t *testing.T) {
+	exists := func(name string) bool { return name == "taken" }
+	var m tea.Model = newFormModel(promptForm{Name: "taken"}, formOptions{AllTags: []string{"review"}, Exists: exists})
+	send := func(msgs ...tea.Msg) {
+		for _, msg := range msgs {
+			m, _ = m.Update(msg)
+		}
+	}
+	typeText := func(s string) {
+		send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+	}
 
-	// Test adding a prompt using external editor
-	err := app.AddPrompt("testprompt", "test,cli", true)
-	if err != nil {
-		t.Errorf("AddPrompt failed: %v", err)
+	problems := strings.Join(m.(editorModel).problems(), "\n")
+	if !strings.Contains(problems, "already exists") || !strings.Contains(problems, "content cannot be empty") {
+		t.Errorf("Expected name and content problems, got %q", problems)
 	}
 
-	// Verify prompt was added
-	prompt, err := store.GetPromptByName("testprompt")
-	if err != nil {
-		t.Errorf("Failed to retrieve added prompt: %v", err)
+	typeText("-2")
+	send(tea.KeyMsg{Type: tea.KeyTab})
+	typeText("rev")
+	send(tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab})
+	typeText("A short description")
+	send(tea.KeyMsg{Type: tea.KeyEnter})
+	send(tea.KeyMsg{Type: tea.KeyCtrlD})
+	if m.(editorModel).saved {
+		t.Fatal("Expected save to be blocked while the body is empty")
 	}
-	if prompt.Name != "testprompt" {
-		t.Errorf("Expected prompt name 'testprompt', got '%s'", prompt.Name)
+	typeText("Review this")
+	send(tea.KeyMsg{Type: tea.KeyCtrlD})
+
+	got := m.(editorModel)
+	want := promptForm{Name: "taken-2", Tags: "review", Description: "A short description", Body: "Review this"}
+	if !got.saved || got.form() != want {
+		t.Errorf("Got %+v (saved %v), want %+v", got.form(), got.saved, want)
 	}
 }
 
-func TestListPromptsWithTags(t *testing.T) {
-	store, dbPath := setupTestDB(t)
-	app := NewApp(store, dbPath)
-
-	// Add test prompts
-	err := store.AddPrompt("test1", "content1", "tag1,tag2")
-	if err != nil {
-		t.Fatal(err)
+func TestEditorLayout(t *testing.T) {
+	var m tea.Model = initialEditorModel("Review {{lang}} code\nsecond line")
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	got := m.(editorModel)
+	if got.ta.Height() != 40-bodyOnlyChromeHeight {
+		t.Errorf("Expected textarea to fill the terminal, got height %d", got.ta.Height())
 	}
-	err = store.AddPrompt("test2", "content2", "tag2,tag3")
-	if err != nil {
-		t.Fatal(err)
+
+	lineNumbers := got.ta.ShowLineNumbers
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	if m.(editorModel).ta.ShowLineNumbers == lineNumbers {
+		t.Error("Expected Ctrl+L to toggle line numbers")
 	}
 
-	// Test filtering by tags
-	prompts, err := app.ListPrompts("tag2")
-	if err != nil {
-		t.Errorf("ListPrompts with tags failed: %v", err)
+	status := m.(editorModel).statusBar()
+	for _, want := range []string{"Ln 2/2", "32 chars", "~8 tokens", fmt.Sprintf("%d left", prompts.MaxPromptContentLen-32)} {
+		if !strings.Contains(status, want) {
+			t.Errorf("Status bar %q missing %q", status, want)
+		}
 	}
-	if len(prompts) != 2 {
-		t.Errorf("Expected 2 prompts with tag2, got %d", len(prompts))
+
+	// The size is of the body as it will be stored: only the configured
+	// normalization is applied, and inner whitespace counts
+	body := "  indented\n\n"
+	for _, tt := range []struct {
+		normalization prompts.Normalization
+		want          int
+	}{
+		{prompts.NormalizeNone, len(body)},
+		{prompts.NormalizeTrailingNewline, len(body) - 1},
+		{prompts.NormalizeFull, len("indented")},
+	} {
+		model := initialEditorModel(body)
+		model.normalization = tt.normalization
+		if status := model.statusBar(); !strings.Contains(status, fmt.Sprintf(" %d chars", tt.want)) {
+			t.Errorf("With %s normalization, status bar %q should count %d chars", tt.normalization, status, tt.want)
+		}
 	}
 }
 
-func TestDeletePromptIntegration(t *testing.T) {
-	store, dbPath := setupTestDB(t)
-	app := NewApp(store, dbPath)
+func TestEditorUndoFindReplace(t *testing.T) {
+	var m tea.Model = initialEditorModel("hello")
+	send := func(msgs ...tea.Msg) editorModel {
+		for _, msg := range msgs {
+			m, _ = m.Update(msg)
+		}
+		return m.(editorModel)
+	}
+	runes := func(s string) tea.Msg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	key := func(k tea.KeyType) tea.Msg { return tea.KeyMsg{Type: k} }
 
-	// Add a test prompt
-	err := store.AddPrompt("testdelete", "test content", "test")
-	if err != nil {
-		t.Fatal(err)
+	for _, r := range " big world" {
+		send(runes(string(r)))
+	}
+	if got := send(key(tea.KeyCtrlZ)).ta.Value(); got != "hello big " {
+		t.Errorf("Expected undo to remove the last word, got %q", got)
+	}
+	if got := send(key(tea.KeyCtrlZ), key(tea.KeyCtrlZ), key(tea.KeyCtrlZ), key(tea.KeyCtrlZ)).ta.Value(); got != "hello" {
+		t.Errorf("Expected undo back to the start, got %q", got)
+	}
+	if got := send(key(tea.KeyCtrlY), key(tea.KeyCtrlY), key(tea.KeyCtrlY), key(tea.KeyCtrlY)).ta.Value(); got != "hello big world" {
+		t.Errorf("Expected redo to restore the text, got %q", got)
 	}
 
-	// Delete the prompt
-	err = app.DeletePrompt("testdelete")
-	if err != nil {
-		t.Errorf("DeletePrompt failed: %v", err)
+	got := send(key(tea.KeyCtrlF), runes("BIG"))
+	if snap := got.snapshot(); snap.col != 6 || got.notice != "1 matches" {
+		t.Errorf("Expected incremental search to move to column 6, got %+v %q", snap, got.notice)
 	}
+	send(key(tea.KeyEsc))
 
-	// Verify prompt was deleted
-	_, err = store.GetPromptByName("testdelete")
-	if err == nil {
-		t.Error("Expected error when retrieving deleted prompt, got nil")
+	// The replace bar keeps the last search
+	got = send(key(tea.KeyCtrlR), key(tea.KeyTab), runes("small"), key(tea.KeyEnter))
+	if got.ta.Value() != "hello small world" || got.notice != "Replaced 1" {
+		t.Errorf("Unexpected replace result %q %q", got.ta.Value(), got.notice)
+	}
+	send(key(tea.KeyEsc))
+	if got := send(key(tea.KeyCtrlZ)).ta.Value(); got != "hello big world" {
+		t.Errorf("Expected replace to undo in one step, got %q", got)
+	}
+
+	if got := send(key(tea.KeyEsc)); !got.confirming || got.quitting {
+		t.Fatal("Expected Esc with unsaved changes to ask for confirmation")
+	}
+	if got := send(runes("n")); got.confirming || got.quitting {
+		t.Error("Expected n to return to the editor")
+	}
+	if got := send(key(tea.KeyEsc), runes("y")); !got.quitting || got.saved {
+		t.Error("Expected y to discard and quit without saving")
 	}
 }
 
-func TestCLIWorkflowIntegration(t *testing.T) {
-	store, dbPath := setupTestDB(t)
-	app := NewApp(store, dbPath)
+func TestEditorFindHighlight(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+	escapes := regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	highlighted := regexp.MustCompile(`\x1b\[48;5;58m([^\x1b]*)\x1b\[0m`)
 
-	// Test full workflow: add, list, edit, delete
+	tests := []struct {
+		query string
+		want  string // the highlighted runs, joined
+	}{
+		{"5", "5"},  // not the 5 in the 256-color escape sequences
+		{"1", "11"}, // not the line numbers in the gutter
+		{"LANG", "lang"},
+	}
+	for _, tt := range tests {
+		var m tea.Model = initialEditorModel("Use {{lang}} 1\n5 more 1")
+		m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.query)})
+		model := m.(editorModel)
+		if !model.ta.ShowLineNumbers {
+			t.Fatal("Expected line numbers to be shown")
+		}
+
+		view := model.bodyView()
+		plain := escapes.ReplaceAllString(model.ta.View(), "")
+		if got := escapes.ReplaceAllString(view, ""); !strings.HasPrefix(got, plain+"\n") {
+			t.Errorf("Find %q changed the visible text:\n%q\nwant\n%q", tt.query, got, plain)
+		}
+		var runs strings.Builder
+		for _, match := range highlighted.FindAllStringSubmatch(view, -1) {
+			runs.WriteString(match[1])
+		}
+		if runs.String() != tt.want {
+			t.Errorf("Find %q highlighted %q, want %q", tt.query, runs.String(), tt.want)
+		}
+	}
+}
 
-	// Add a prompt directly to store (since AddPrompt requires editor interaction)
-	err := store.AddPrompt("workflow-test", "initial content", "workflow,test")
-	if err != nil {
-		t.Fatalf("Failed to add prompt: %v", err)
+// fakeEditor installs a shell script as $EDITOR, clearing the variables that
+// take precedence over it, and returns the script's path.
+func fakeEditor(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
 	}
+	t.Setenv(editorEnv, "")
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", path)
+	return path
+}
 
-	// List all prompts
-	prompts, err := app.ListPrompts("")
-	if err != nil {
-		t.Fatalf("Failed to list prompts: %v", err)
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"vim", []string{"vim"}, false},
+		{"  code --wait ", []string{"code", "--wait"}, false},
+		{`emacsclient -t -a ""`, []string{"emacsclient", "-t", "-a", ""}, false},
+		{`'/Applications/My Editor' --new`, []string{"/Applications/My Editor", "--new"}, false},
+		{`"a \"b\" c\d" e\ f`, []string{`a "b" c\d`, "e f"}, false},
+		{`vim 'oops`, nil, true},
+		{`vim \`, nil, true},
 	}
-	if len(prompts) != 1 {
-		t.Errorf("Expected 1 prompt, got %d", len(prompts))
+	for _, tt := range tests {
+		got, err := splitShellWords(tt.in)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitShellWords(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
 	}
+}
 
-	// List with tag filter (OR logic)
-	prompts, err = app.ListPrompts("workflow")
-	if err != nil {
-		t.Fatalf("Failed to list prompts with tag filter: %v", err)
+func TestEditorCommand(t *testing.T) {
+	// The script logs its arguments and writes to the .md file it is given
+	script := `log="$(dirname "$0")/args"; for a; do printf "%s\n" "$a" >> "$log"; done; for a; do case "$a" in *.md) echo edited > "${a#--file=}";; esac; done`
+	tests := []struct {
+		name    string
+		env     map[string]string
+		line    int
+		wantArg []string // expected arguments, with FILE standing for the temp file
+	}{
+		{"editor", map[string]string{}, 0, []string{"FILE"}},
+		{"editor with args", map[string]string{"EDITOR": "SCRIPT --wait"}, 0, []string{"--wait", "FILE"}},
+		{"visual preferred", map[string]string{"VISUAL": "SCRIPT -v", "EDITOR": "false"}, 0, []string{"-v", "FILE"}},
+		{"template", map[string]string{editorEnv: "SCRIPT +{line} --file={file} -n", "VISUAL": "false"}, 7, []string{"+7", "--file=FILE", "-n"}},
 	}
-	if len(prompts) != 1 {
-		t.Errorf("Expected 1 prompt with workflow tag, got %d", len(prompts))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := fakeEditor(t, script)
+			for k, v := range tt.env {
+				t.Setenv(k, strings.ReplaceAll(v, "SCRIPT", path))
+			}
+			var out bytes.Buffer
+			content, result, err := externalEditor{in: strings.NewReader(""), out: &out, err: &out, line: tt.line}.Edit("")
+			if err != nil || result != editSaved || content != "edited\n" {
+				t.Fatalf("Edit() = %q, %v, %v; output:\n%s", content, result, err, out.String())
+			}
+			data, err := os.ReadFile(filepath.Join(filepath.Dir(path), "args"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := strings.Split(strings.TrimSpace(string(data)), "\n")
+			file := regexp.MustCompile(`p-prompt-\d+\.md`)
+			for i, a := range args {
+				if m := file.FindString(a); m != "" {
+					args[i] = strings.Replace(a, filepath.Join(os.TempDir(), m), "FILE", 1)
+				}
+			}
+			if !reflect.DeepEqual(args, tt.wantArg) {
+				t.Errorf("editor arguments = %q, want %q", args, tt.wantArg)
+			}
+		})
 	}
+}
 
-	// List with AND logic
-	prompts, err = app.ListPrompts("AND:workflow,test")
-	if err != nil {
-		t.Fatalf("Failed to list prompts with AND logic: %v", err)
+func TestEditorResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		initial string
+		want    editResult
+		content string
+	}{
+		{"untouched", "true", "keep me", editCancelled, ""},
+		{"untouched new", "true", "", editCancelled, ""},
+		{"emptied", `: > "$1"`, "keep me", editCancelled, ""},
+		{"saved", `echo "new text" > "$1"`, "keep me", editSaved, "new text\n"},
 	}
-	if len(prompts) != 1 {
-		t.Errorf("Expected 1 prompt with both tags, got %d", len(prompts))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeEditor(t, tt.script)
+			var out bytes.Buffer
+			content, result, err := externalEditor{in: strings.NewReader(""), out: &out, err: &out}.Edit(tt.initial)
+			if err != nil || result != tt.want || content != tt.content {
+				t.Errorf("Edit() = %q, %v, %v; want %q, %v", content, result, err, tt.content, tt.want)
+			}
+		})
 	}
 
-	// Edit the prompt
-	err = app.EditPrompt(&prompts[0], "updated content", "workflow,updated")
-	if err != nil {
-		t.Fatalf("Failed to edit prompt: %v", err)
+	var m tea.Model = initialEditorModel("body")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+	if got := m.(editorModel).result(); got != editUnchanged {
+		t.Errorf("Expected saving an untouched body to be unchanged, got %v", got)
+	}
+	m = initialEditorModel("body")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("!")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if got := m.(editorModel).result(); got != editCancelled {
+		t.Errorf("Expected Ctrl+C to cancel, got %v", got)
 	}
 
-	// Verify edit
-	updatedPrompt, err := store.GetPromptByName("workflow-test")
-	if err != nil {
-		t.Fatalf("Failed to get updated prompt: %v", err)
+	app := setupTestApp(t)
+	if _, err := app.CreatePrompt("kept", "original", ""); err != nil {
+		t.Fatal(err)
 	}
-	if updatedPrompt.Prompt != "updated content" {
-		t.Errorf("Expected updated content, got %s", updatedPrompt.Prompt)
+	fakeEditor(t, "true")
+	out, err := runCmd(newEditCmd(app), "", "kept", "--external-editor")
+	if err != nil || !strings.Contains(out, "Operation cancelled") {
+		t.Errorf("Expected untouched external edit to cancel, got %q, %v", out, err)
 	}
-	if updatedPrompt.Tags != "updated,workflow" { // normalized order
-		t.Errorf("Expected updated tags, got %s", updatedPrompt.Tags)
+	out, err = runCmd(newAddCmd(app), "", "new", "--external-editor")
+	if err != nil || !strings.Contains(out, "No prompt added") {
+		t.Errorf("Expected untouched external add to cancel, got %q, %v", out, err)
 	}
+}
 
-	// Delete the prompt
-	err = app.DeletePrompt("workflow-test")
-	if err != nil {
-		t.Fatalf("Failed to delete prompt: %v", err)
+func TestEditReview(t *testing.T) {
+	app := setupTestApp(t)
+	existing := &prompts.Prompt{Name: "greet", Prompt: "hello\nworld", Tags: "x"}
+	tests := []struct {
+		name    string
+		script  string
+		answers string
+		want    string
+		wantErr error
+	}{
+		{"save", `echo "hello there" > "$1"`, "s\n", "hello there\n", nil},
+		{"discard", `echo "hello there" > "$1"`, "d\n", "", prompts.ErrCancelled},
+		{"retry answer", `echo "hello there" > "$1"`, "maybe\nyes\n", "hello there\n", nil},
+		{"unchanged skips review", "true", "", "", prompts.ErrCancelled},
+		{"edit again", `printf "\nmore" >> "$1"`, "e\ns\n", "hello\nworld\nmore\nmore", nil},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeEditor(t, tt.script)
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetIn(strings.NewReader(tt.answers))
+			cmd.SetOut(&out)
+			editor := externalEditor{in: strings.NewReader(""), out: &out, err: &out}
+			content, err := reviewedContent(cmd, app, editor, existing, existing.Tags).Content(existing.Prompt)
+			if !errors.Is(err, tt.wantErr) || content != tt.want {
+				t.Errorf("Content() = %q, %v; want %q, %v\n%s", content, err, tt.want, tt.wantErr, out.String())
+			}
+		})
+	}
+}
 
-	// Verify deletion
-	_, err = store.GetPromptByName("workflow-test")
-	if err == nil {
-		t.Error("Expected error when retrieving deleted prompt, got nil")
+func TestPrintEditDiff(t *testing.T) {
+	before := promptForm{Name: "a", Tags: "x", Body: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10"}
+	after := promptForm{Name: "b", Tags: "x,y", Body: "1\n2\n3\n4\n5\n6\n7\n8\nnine\n10"}
+	var out bytes.Buffer
+	printEditDiff(&out, before, after)
+	got := out.String()
+	for _, want := range []string{"Name: a -> b", "Tags: x -> x,y", "⋮ 5 unchanged lines", "-9", "+nine", " 10"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("printEditDiff() output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Description") {
+		t.Errorf("printEditDiff() shows unchanged description:\n%s", got)
+	}
+	if formChanged(before, promptForm{Name: "a", Tags: " x ", Body: before.Body}) {
+		t.Error("formChanged() should ignore tag formatting")
 	}
 }
@@ -0,0 +1,119 @@
+package prompts
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sqliteHeader is the magic string every SQLite 3 database file starts with.
+const sqliteHeader = "SQLite format 3\x00"
+
+// DBInfo summarizes a database file for restore validation and dry runs.
+type DBInfo struct {
+	SchemaVersion int
+	PromptCount   int
+}
+
+// InspectDatabase opens a database file read-only and reports its schema version and prompt count.
+func InspectDatabase(path string) (*DBInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database file: %w", err)
+	}
+	header := make([]byte, len(sqliteHeader))
+	_, err = io.ReadFull(f, header)
+	f.Close()
+	if err != nil || !bytes.Equal(header, []byte(sqliteHeader)) {
+		return nil, fmt.Errorf("%s is not a SQLite database", path)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	var info DBInfo
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return nil, fmt.Errorf("%s has no schema_migrations table: %w", path, err)
+	}
+	info.SchemaVersion = int(version.Int64)
+	if err := db.QueryRow("SELECT COUNT(*) FROM prompts").Scan(&info.PromptCount); err != nil {
+		return nil, fmt.Errorf("%s has no prompts table: %w", path, err)
+	}
+	return &info, nil
+}
+
+// VerifyBackup checks that a backup is a SQLite database this version of p can open.
+func VerifyBackup(path string) (*DBInfo, error) {
+	info, err := InspectDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.SchemaVersion < 1 {
+		return nil, fmt.Errorf("backup %s has no applied migrations", path)
+	}
+	if info.SchemaVersion > currentSchemaVersion {
+		return nil, fmt.Errorf("backup schema version %d is newer than supported version %d", info.SchemaVersion, currentSchemaVersion)
+	}
+	return info, nil
+}
+
+// copyFile copies src to dst and syncs dst to disk.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// snapshotDatabase copies the database next to itself with a timestamped suffix and returns the copy's path.
+func snapshotDatabase(dbPath string) (string, error) {
+	snapshotPath := fmt.Sprintf("%s.pre-restore-%s", dbPath, time.Now().Format("20060102-150405"))
+	if err := copyFile(dbPath, snapshotPath); err != nil {
+		return "", fmt.Errorf("error snapshotting database: %w", err)
+	}
+	return snapshotPath, nil
+}
+
+// replaceDatabase atomically swaps the database at dbPath for a copy of backupPath.
+// The backup is staged in the same directory so the final rename never crosses filesystems.
+func replaceDatabase(dbPath, backupPath string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dbPath), ".restore-*.db")
+	if err != nil {
+		return fmt.Errorf("error creating staging file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+
+	if err := copyFile(backupPath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error staging backup: %w", err)
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error replacing database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package prompts
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrCancelled is returned when a content provider yields no content, e.g. the user quit the editor.
+var ErrCancelled = errors.New("operation cancelled")
+
+// ContentProvider supplies the body of a prompt. initial is the current
+// content when editing and empty when adding.
+type ContentProvider interface {
+	Content(initial string) (string, error)
+}
+
+// ContentFunc adapts a function to a ContentProvider.
+type ContentFunc func(initial string) (string, error)
+
+// Content calls f(initial).
+func (f ContentFunc) Content(initial string) (string, error) {
+	return f(initial)
+}
+
+// StringContent is a fixed body, such as one passed on the command line. The initial content is ignored.
+type StringContent string
+
+// Content returns the string.
+func (c StringContent) Content(string) (string, error) {
+	return nonInteractiveContent(string(c))
+}
+
+// ReaderContent reads the whole body from R, such as stdin. The initial content is ignored.
+type ReaderContent struct {
+	R io.Reader
+}
+
+// Content reads R to the end.
+func (c ReaderContent) Content(string) (string, error) {
+	data, err := io.ReadAll(c.R)
+	if err != nil {
+		return "", fmt.Errorf("error reading prompt content: %w", err)
+	}
+	return nonInteractiveContent(string(data))
+}
+
+// FileContent reads the body from the file at Path. The initial content is ignored.
+type FileContent struct {
+	Path string
+}
+
+// Content reads the file.
+func (c FileContent) Content(string) (string, error) {
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return "", fmt.Errorf("error reading prompt content: %w", err)
+	}
+	return nonInteractiveContent(string(data))
+}
+
+// nonInteractiveContent checks content that did not come from an editor.
+// Empty content there is a mistake rather than a cancellation, so it is rejected.
+// The length is validated when the prompt is saved, after normalization.
+func nonInteractiveContent(content string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", invalidf("prompt content cannot be empty")
+	}
+	return content, nil
+}
+
+// EditResult reports the outcome of EditPromptWith.
+type EditResult struct {
+	Prompt  *Prompt // the prompt after the edit
+	Changed bool    // false when neither content nor tags differed
+}
+
+// AddPromptWith validates name, asks content for the body, and stores the new prompt.
+// It returns ErrCancelled if the provider does, or yields empty content.
+func (a *App) AddPromptWith(name, tags string, content ContentProvider) (*Prompt, error) {
+	if err := ValidatePromptName(name); err != nil {
+		return nil, err
+	}
+	body, err := content.Content("")
+	if errors.Is(err, ErrCancelled) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting prompt content: %w", err)
+	}
+	if strings.TrimSpace(body) == "" {
+		return nil, ErrCancelled
+	}
+	return a.CreatePrompt(name, body, tags)
+}
+
+// EditPromptWith asks content for a new body for the named prompt and saves it.
+// A nil tags keeps the current tags. It returns ErrCancelled if the provider does, or yields empty content.
+func (a *App) EditPromptWith(name string, tags *string, content ContentProvider) (*EditResult, error) {
+	existing, err := a.GetPrompt(name)
+	if err != nil {
+		return nil, err
+	}
+	newTags := existing.Tags
+	if tags != nil {
+		newTags = *tags
+	}
+	body, err := content.Content(existing.Prompt)
+	if errors.Is(err, ErrCancelled) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting prompt content: %w", err)
+	}
+	if strings.TrimSpace(body) == "" {
+		return nil, ErrCancelled
+	}
+	changed, err := a.EditPrompt(existing, body, newTags)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return &EditResult{Prompt: existing, Changed: false}, nil
+	}
+	updated, err := a.GetPrompt(name)
+	if err != nil {
+		return nil, err
+	}
+	return &EditResult{Prompt: updated, Changed: true}, nil
+}
@@ -0,0 +1,359 @@
+package prompts
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type Prompt struct {
+	ID          int
+	Name        string
+	Prompt      string
+	Tags        string
+	Description string
+	Pinned      bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// promptColumns is the column list every prompt query selects, in scanPrompt order.
+const promptColumns = "id, name, prompt, COALESCE(tags, ''), COALESCE(description, ''), pinned, created_at, updated_at"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPrompt scans a row selected with promptColumns into a Prompt.
+func scanPrompt(row rowScanner) (Prompt, error) {
+	var p Prompt
+	var createdAt, updatedAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.Name, &p.Prompt, &p.Tags, &p.Description, &p.Pinned, &createdAt, &updatedAt); err != nil {
+		return p, err
+	}
+	p.CreatedAt = createdAt.Time
+	p.UpdatedAt = updatedAt.Time
+	return p, nil
+}
+
+// queryer is the subset of *sql.DB and *sql.Tx the store needs to run queries.
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// SQLitePromptStore manages prompts using SQLite database.
+// A store returned by Begin runs every query inside its transaction.
+type SQLitePromptStore struct {
+	db *sql.DB
+	q  queryer
+	tx *sql.Tx
+}
+
+func NewSQLitePromptStore(db *sql.DB) *SQLitePromptStore {
+	return &SQLitePromptStore{db: db, q: db}
+}
+
+// Begin starts a transaction and returns a store bound to it.
+// Callers must finish it with Commit or Rollback.
+func (s *SQLitePromptStore) Begin() (*SQLitePromptStore, error) {
+	if s.tx != nil {
+		return nil, fmt.Errorf("transaction already in progress")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	return &SQLitePromptStore{db: s.db, q: tx, tx: tx}, nil
+}
+
+// Commit commits the store's transaction. It is a no-op outside a transaction.
+func (s *SQLitePromptStore) Commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	if err := s.tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Rollback aborts the store's transaction. It is a no-op outside a transaction.
+func (s *SQLitePromptStore) Rollback() error {
+	if s.tx == nil {
+		return nil
+	}
+	return s.tx.Rollback()
+}
+
+// Close closes the underlying database connection.
+func (s *SQLitePromptStore) Close() error {
+	return s.db.Close()
+}
+
+// AddPrompt inserts a new prompt into the database.
+func (s *SQLitePromptStore) AddPrompt(name, prompt, tags string) error {
+	query := "INSERT INTO prompts (name, prompt, tags, created_at, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)"
+	_, err := s.q.Exec(query, name, prompt, tags)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("prompt name '%s' %w", name, ErrDuplicate)
+		}
+		return fmt.Errorf("error adding prompt: %w", err)
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is SQLite rejecting a duplicate key.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// GetPromptByName retrieves a prompt by its name from the database.
+func (s *SQLitePromptStore) GetPromptByName(name string) (*Prompt, error) {
+	p, err := s.FindPromptByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("prompt '%s' %w", name, ErrNotFound)
+	}
+	return p, nil
+}
+
+// FindPromptByName retrieves a prompt by its name, returning nil without an error if it does not exist.
+func (s *SQLitePromptStore) FindPromptByName(name string) (*Prompt, error) {
+	query := "SELECT " + promptColumns + " FROM prompts WHERE name = ?"
+	p, err := scanPrompt(s.q.QueryRow(query, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error scanning prompt: %w", err)
+	}
+	return &p, nil
+}
+
+// UpdatePrompt modifies an existing prompt's content and tags in the database.
+func (s *SQLitePromptStore) UpdatePrompt(name, newPrompt, newTags string) error {
+	query := "UPDATE prompts SET prompt = ?, tags = ?, updated_at = CURRENT_TIMESTAMP WHERE name = ?"
+	result, err := s.q.Exec(query, newPrompt, newTags, name)
+	if err != nil {
+		return fmt.Errorf("error updating prompt: %w", err)
+	}
+	return requireRow(result, name)
+}
+
+// SetDescription replaces a prompt's description.
+func (s *SQLitePromptStore) SetDescription(name, description string) error {
+	result, err := s.q.Exec("UPDATE prompts SET description = ?, updated_at = CURRENT_TIMESTAMP WHERE name = ?", description, name)
+	if err != nil {
+		return fmt.Errorf("error updating prompt: %w", err)
+	}
+	return requireRow(result, name)
+}
+
+// SetPinned pins or unpins a prompt. Pinning is not an edit, so updated_at is kept.
+func (s *SQLitePromptStore) SetPinned(name string, pinned bool) error {
+	result, err := s.q.Exec("UPDATE prompts SET pinned = ? WHERE name = ?", pinned, name)
+	if err != nil {
+		return fmt.Errorf("error pinning prompt: %w", err)
+	}
+	return requireRow(result, name)
+}
+
+// RenamePrompt changes a prompt's name.
+func (s *SQLitePromptStore) RenamePrompt(oldName, newName string) error {
+	result, err := s.q.Exec("UPDATE prompts SET name = ?, updated_at = CURRENT_TIMESTAMP WHERE name = ?", newName, oldName)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("prompt name '%s' %w", newName, ErrDuplicate)
+		}
+		return fmt.Errorf("error renaming prompt: %w", err)
+	}
+	return requireRow(result, oldName)
+}
+
+// requireRow returns ErrNotFound if an UPDATE or DELETE on the named prompt matched nothing.
+func requireRow(result sql.Result, name string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("prompt '%s' %w", name, ErrNotFound)
+	}
+	return nil
+}
+
+// DeletePrompt removes a prompt from the database by name.
+func (s *SQLitePromptStore) DeletePrompt(name string) error {
+	query := "DELETE FROM prompts WHERE name = ?"
+	result, err := s.q.Exec(query, name)
+	if err != nil {
+		return fmt.Errorf("error deleting prompt: %w", err)
+	}
+	return requireRow(result, name)
+}
+
+// ListPrompts retrieves all prompts from the database.
+func (s *SQLitePromptStore) ListPrompts() ([]Prompt, error) {
+	return s.ListPromptsWhere("1")
+}
+
+// ListPromptsWhere retrieves the prompts matching an SQL condition, ordered by name.
+func (s *SQLitePromptStore) ListPromptsWhere(where string, args ...any) ([]Prompt, error) {
+	query := "SELECT " + promptColumns + " FROM prompts WHERE " + where + " ORDER BY name"
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing prompts: %w", err)
+	}
+	defer rows.Close()
+
+	var prompts []Prompt
+	for rows.Next() {
+		p, err := scanPrompt(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		prompts = append(prompts, p)
+	}
+	return prompts, nil
+}
+
+// GetSetting returns the value stored for key, or an empty string if it is unset.
+func (s *SQLitePromptStore) GetSetting(key string) (string, error) {
+	var value string
+	err := s.q.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading setting '%s': %w", key, err)
+	}
+	return value, nil
+}
+
+// SetSetting stores value under key, replacing any previous value.
+func (s *SQLitePromptStore) SetSetting(key, value string) error {
+	query := "INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value"
+	if _, err := s.q.Exec(query, key, value); err != nil {
+		return fmt.Errorf("error saving setting '%s': %w", key, err)
+	}
+	return nil
+}
+
+// GetDirSyncState returns the hash of each prompt as it was when dir was last synced, keyed by prompt name.
+func (s *SQLitePromptStore) GetDirSyncState(dir string) (map[string]string, error) {
+	rows, err := s.q.Query("SELECT name, hash FROM dir_sync_state WHERE dir = ?", dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading sync state: %w", err)
+	}
+	defer rows.Close()
+
+	state := make(map[string]string)
+	for rows.Next() {
+		var name, hash string
+		if err := rows.Scan(&name, &hash); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		state[name] = hash
+	}
+	return state, rows.Err()
+}
+
+// SetDirSyncState records the hash of a prompt as synced for dir. An empty hash removes the record.
+func (s *SQLitePromptStore) SetDirSyncState(dir, name, hash string) error {
+	var err error
+	if hash == "" {
+		_, err = s.q.Exec("DELETE FROM dir_sync_state WHERE dir = ? AND name = ?", dir, name)
+	} else {
+		query := "INSERT INTO dir_sync_state (dir, name, hash) VALUES (?, ?, ?) ON CONFLICT(dir, name) DO UPDATE SET hash = excluded.hash"
+		_, err = s.q.Exec(query, dir, name, hash)
+	}
+	if err != nil {
+		return fmt.Errorf("error saving sync state: %w", err)
+	}
+	return nil
+}
+
+// ListPromptsByTags retrieves prompts filtered by tags using proper set-based filtering.
+// Supports AND/OR logic: "tag1,tag2" (OR) or "AND:tag1,tag2" (AND)
+func (s *SQLitePromptStore) ListPromptsByTags(tagsFilter string) ([]Prompt, error) {
+	// Get all prompts and filter in memory for proper tag matching
+	allPrompts, err := s.ListPrompts()
+	if err != nil {
+		return nil, fmt.Errorf("error listing all prompts: %w", err)
+	}
+	if tagsFilter == "" {
+		return allPrompts, nil
+	}
+
+	// Parse filter logic and tags
+	var filterTags []string
+	useAndLogic := false
+	if strings.HasPrefix(tagsFilter, "AND:") {
+		useAndLogic = true
+		tagsFilter = strings.TrimPrefix(tagsFilter, "AND:")
+	}
+
+	for _, tag := range strings.Split(tagsFilter, ",") {
+		if trimmed := strings.TrimSpace(tag); trimmed != "" {
+			filterTags = append(filterTags, trimmed)
+		}
+	}
+
+	if len(filterTags) == 0 {
+		return allPrompts, nil
+	}
+
+	var filteredPrompts []Prompt
+	for _, prompt := range allPrompts {
+		if prompt.Tags == "" {
+			continue
+		}
+
+		// Split prompt tags into a set
+		promptTagSet := make(map[string]struct{})
+		for _, tag := range strings.Split(prompt.Tags, ",") {
+			if trimmed := strings.TrimSpace(tag); trimmed != "" {
+				promptTagSet[trimmed] = struct{}{}
+			}
+		}
+
+		if useAndLogic {
+			// AND logic: prompt must have ALL filter tags
+			hasAllTags := true
+			for _, filterTag := range filterTags {
+				if _, exists := promptTagSet[filterTag]; !exists {
+					hasAllTags = false
+					break
+				}
+			}
+			if hasAllTags {
+				filteredPrompts = append(filteredPrompts, prompt)
+			}
+		} else {
+			// OR logic: prompt must have ANY filter tag
+			hasAnyTag := false
+			for _, filterTag := range filterTags {
+				if _, exists := promptTagSet[filterTag]; exists {
+					hasAnyTag = true
+					break
+				}
+			}
+			if hasAnyTag {
+				filteredPrompts = append(filteredPrompts, prompt)
+			}
+		}
+	}
+
+	return filteredPrompts, nil
+}
@@ -0,0 +1,38 @@
+package prompts
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error classes returned by the store and App. Check them with errors.Is;
+// the concrete errors carry the details, such as the prompt name.
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("already exists")
+	ErrInvalid   = errors.New("invalid input")
+)
+
+// invalidError is an ErrInvalid with its own message.
+type invalidError struct {
+	msg string
+}
+
+func (e *invalidError) Error() string {
+	return e.msg
+}
+
+func (e *invalidError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// invalidf formats an error that matches ErrInvalid.
+func invalidf(format string, args ...any) error {
+	return &invalidError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Invalidf formats an error that matches ErrInvalid, for callers outside the
+// package such as the command line rejecting a flag value.
+func Invalidf(format string, args ...any) error {
+	return invalidf(format, args...)
+}
@@ -0,0 +1,524 @@
+package prompts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported export/import formats.
+const (
+	FormatJSON     = "json"
+	FormatJSONL    = "jsonl"
+	FormatYAML     = "yaml"
+	FormatMarkdown = "md"
+	FormatCSV      = "csv"
+)
+
+// Formats lists the valid --format values in the order shown to users.
+var Formats = []string{FormatJSON, FormatJSONL, FormatYAML, FormatMarkdown, FormatCSV}
+
+// promptRecord is the JSON/CSV shape of an exported prompt.
+// Field matching in encoding/json is case-insensitive, so exports from older versions still import.
+type promptRecord struct {
+	ID          int    `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Prompt      string `json:"prompt"`
+	Tags        string `json:"tags"`
+	Description string `json:"description,omitempty"`
+}
+
+// yamlRecord is the YAML and Markdown front matter shape of an exported prompt.
+type yamlRecord struct {
+	ID          int      `yaml:"id,omitempty"`
+	Name        string   `yaml:"name"`
+	Tags        []string `yaml:"tags,omitempty,flow"`
+	Description string   `yaml:"description,omitempty"`
+	Prompt      string   `yaml:"prompt,omitempty"`
+}
+
+// DetectFormat picks a format from an explicit flag value, falling back to the file extension.
+// Directories, paths ending in a separator and .md files are treated as Markdown,
+// and stdin/stdout ("-") as JSON.
+func DetectFormat(path, explicit string) (string, error) {
+	if explicit != "" {
+		for _, f := range Formats {
+			if f == explicit {
+				return f, nil
+			}
+		}
+		return "", invalidf("invalid format '%s', must be one of: %s", explicit, strings.Join(Formats, ", "))
+	}
+	if path == "-" {
+		return FormatJSON, nil
+	}
+	if endsInSeparator(path) {
+		return FormatMarkdown, nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return FormatMarkdown, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return FormatMarkdown, nil
+	}
+	return FormatJSON, nil
+}
+
+// endsInSeparator reports whether path names a directory by ending in a path separator.
+func endsInSeparator(path string) bool {
+	return strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator))
+}
+
+// IsMarkdownFile reports whether a Markdown path names a single file rather
+// than a directory: it has a .md or .markdown extension, no trailing separator,
+// and is not an existing directory.
+func IsMarkdownFile(path string) bool {
+	if endsInSeparator(path) || !hasMarkdownExt(path) {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err != nil || !info.IsDir()
+}
+
+// hasMarkdownExt reports whether path ends in .md or .markdown, in any case.
+func hasMarkdownExt(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// SplitTags turns a normalized comma-separated tag string into a slice.
+func SplitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(NormalizeTags(tags), ",")
+}
+
+// WritePrompts encodes prompts to w in a single-stream format.
+func WritePrompts(w io.Writer, format string, prompts []Prompt, includeIDs bool) error {
+	records := make([]promptRecord, len(prompts))
+	for i, p := range prompts {
+		records[i] = promptRecord{Name: p.Name, Prompt: p.Prompt, Tags: p.Tags, Description: p.Description}
+		if includeIDs {
+			records[i].ID = p.ID
+		}
+	}
+
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling prompts: %w", err)
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return fmt.Errorf("error marshaling prompt '%s': %w", r.Name, err)
+			}
+		}
+		return nil
+	case FormatYAML:
+		yamlRecords := make([]yamlRecord, len(records))
+		for i, r := range records {
+			yamlRecords[i] = yamlRecord{ID: r.ID, Name: r.Name, Tags: SplitTags(r.Tags), Description: r.Description, Prompt: r.Prompt}
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(yamlRecords); err != nil {
+			return fmt.Errorf("error marshaling prompts: %w", err)
+		}
+		return enc.Close()
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		header := []string{"name", "tags", "description", "prompt"}
+		if includeIDs {
+			header = append([]string{"id"}, header...)
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, r := range records {
+			row := []string{r.Name, r.Tags, r.Description, r.Prompt}
+			if includeIDs {
+				row = append([]string{strconv.Itoa(r.ID)}, row...)
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return invalidf("format '%s' cannot be written to a single stream", format)
+}
+
+// ReadPrompts decodes prompts from r in a single-stream format.
+func ReadPrompts(r io.Reader, format string) ([]Prompt, error) {
+	switch format {
+	case FormatJSON:
+		var records []promptRecord
+		if err := json.NewDecoder(r).Decode(&records); err != nil {
+			return nil, fmt.Errorf("error unmarshaling prompts: %w", err)
+		}
+		return recordsToPrompts(records), nil
+	case FormatJSONL:
+		var records []promptRecord
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*MaxPromptContentLen)
+		for line := 1; scanner.Scan(); line++ {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			var rec promptRecord
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+				return nil, fmt.Errorf("error unmarshaling line %d: %w", line, err)
+			}
+			records = append(records, rec)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading prompts: %w", err)
+		}
+		return recordsToPrompts(records), nil
+	case FormatYAML:
+		var yamlRecords []yamlRecord
+		if err := yaml.NewDecoder(r).Decode(&yamlRecords); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error unmarshaling prompts: %w", err)
+		}
+		prompts := make([]Prompt, len(yamlRecords))
+		for i, rec := range yamlRecords {
+			prompts[i] = Prompt{ID: rec.ID, Name: rec.Name, Prompt: rec.Prompt, Tags: strings.Join(rec.Tags, ","), Description: rec.Description}
+		}
+		return prompts, nil
+	case FormatCSV:
+		return readCSVPrompts(r)
+	}
+	return nil, invalidf("format '%s' cannot be read from a single stream", format)
+}
+
+// recordsToPrompts converts decoded records into prompts.
+func recordsToPrompts(records []promptRecord) []Prompt {
+	prompts := make([]Prompt, len(records))
+	for i, r := range records {
+		prompts[i] = Prompt{ID: r.ID, Name: r.Name, Prompt: r.Prompt, Tags: r.Tags, Description: r.Description}
+	}
+	return prompts
+}
+
+// readCSVPrompts reads prompts from CSV with a header row naming the name, prompt, and optional tags, description and id columns.
+func readCSVPrompts(r io.Reader) ([]Prompt, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, h := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"name", "prompt"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the '%s' column", required)
+		}
+	}
+	field := func(row []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	prompts := make([]Prompt, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		p := Prompt{Name: field(row, "name"), Prompt: field(row, "prompt"), Tags: field(row, "tags"), Description: field(row, "description")}
+		if id := field(row, "id"); id != "" {
+			p.ID, _ = strconv.Atoi(id)
+		}
+		prompts = append(prompts, p)
+	}
+	return prompts, nil
+}
+
+// promptFileName maps a prompt name to a slash-separated path inside a Markdown
+// export directory: each namespace segment becomes a subdirectory, so code/review
+// is written as code/review.md. Characters that are unsafe in file names are
+// percent-escaped, as is % itself, so promptNameFromFile recovers the name exactly.
+func promptFileName(name string) string {
+	segments := strings.Split(name, NamespaceSep)
+	for i, segment := range segments {
+		segments[i] = escapeFileSegment(segment)
+	}
+	return strings.Join(segments, "/") + ".md"
+}
+
+// escapeFileSegment percent-escapes the characters of one name segment that
+// are unsafe in a file name.
+func escapeFileSegment(segment string) string {
+	var b strings.Builder
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		unsafe := strings.IndexByte(`%/\:*?"<>|`, c) >= 0 || c < 0x20 || c == 0x7f ||
+			(i == 0 && c == '.') || // hidden files
+			(i == len(segment)-1 && (c == '.' || c == ' ')) // stripped by Windows
+		if unsafe {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// promptNameFromFile reverses promptFileName for a path relative to the export
+// directory. Hand-written files may also end in .markdown, and a % not followed
+// by two hex digits is kept as is, so their names still load.
+func promptNameFromFile(path string) string {
+	path = filepath.ToSlash(path)
+	segments := strings.Split(strings.TrimSuffix(path, filepath.Ext(path)), "/")
+	for i, segment := range segments {
+		segments[i] = unescapeFileSegment(segment)
+	}
+	return strings.Join(segments, NamespaceSep)
+}
+
+// unescapeFileSegment reverses escapeFileSegment.
+func unescapeFileSegment(escaped string) string {
+	var b strings.Builder
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] == '%' && i+2 < len(escaped) {
+			if c, err := strconv.ParseUint(escaped[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(escaped[i])
+	}
+	return b.String()
+}
+
+// markdownFiles lists the .md and .markdown files under dir as slash-separated
+// relative paths, in path order. Hidden directories such as .git are skipped.
+func markdownFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !hasMarkdownExt(entry.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// writeMarkdownFile writes data to a slash-separated path under dir, creating
+// namespace directories as needed.
+func writeMarkdownFile(dir, file string, data []byte) error {
+	path := filepath.Join(dir, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	return nil
+}
+
+// removeMarkdownFile deletes a slash-separated path under dir, then any
+// namespace directories it leaves empty.
+func removeMarkdownFile(dir, file string) error {
+	if err := os.Remove(filepath.Join(dir, filepath.FromSlash(file))); err != nil {
+		return fmt.Errorf("error removing file: %w", err)
+	}
+	for parent := path.Dir(file); parent != "."; parent = path.Dir(parent) {
+		// Remove fails on a directory that still has files, which ends the walk
+		if os.Remove(filepath.Join(dir, filepath.FromSlash(parent))) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// checkFileNames rejects prompts whose file names differ only in case, which
+// would overwrite each other on a case-insensitive file system.
+func checkFileNames(prompts []Prompt) error {
+	seen := make(map[string]string, len(prompts))
+	for _, p := range prompts {
+		key := strings.ToLower(promptFileName(p.Name))
+		if other, ok := seen[key]; ok {
+			return invalidf("prompts '%s' and '%s' differ only in case and cannot both be written as files", other, p.Name)
+		}
+		seen[key] = p.Name
+	}
+	return nil
+}
+
+// marshalMarkdown renders a prompt as Markdown with YAML front matter.
+func marshalMarkdown(p Prompt, includeID bool) ([]byte, error) {
+	front := yamlRecord{Name: p.Name, Tags: SplitTags(p.Tags), Description: p.Description}
+	if includeID {
+		front.ID = p.ID
+	}
+	header, err := yaml.Marshal(front)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling front matter: %w", err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(header)
+	buf.WriteString("---\n")
+	buf.WriteString(p.Prompt)
+	return buf.Bytes(), nil
+}
+
+// unmarshalMarkdown parses a Markdown prompt with optional front matter.
+// The front matter delimiters may end in \n or \r\n, as long as both match.
+// Files without a name in their front matter take fallbackName.
+func unmarshalMarkdown(data []byte, fallbackName string) (Prompt, error) {
+	p := Prompt{Name: fallbackName, Prompt: string(data)}
+	text := string(data)
+	newline := "\n"
+	if strings.HasPrefix(text, "---\r\n") {
+		newline = "\r\n"
+	}
+	delim := "---" + newline
+	if !strings.HasPrefix(text, delim) {
+		return p, nil
+	}
+	text = text[len(delim):]
+	if strings.HasPrefix(text, delim) {
+		p.Prompt = text[len(delim):]
+		return p, nil
+	}
+	end := strings.Index(text, newline+delim)
+	if end < 0 {
+		return p, fmt.Errorf("unterminated front matter")
+	}
+	var front yamlRecord
+	if err := yaml.Unmarshal([]byte(text[:end+len(newline)]), &front); err != nil {
+		return p, fmt.Errorf("error parsing front matter: %w", err)
+	}
+	p.Prompt = text[end+len(newline)+len(delim):]
+	p.ID = front.ID
+	p.Tags = strings.Join(front.Tags, ",")
+	p.Description = front.Description
+	if front.Name != "" {
+		p.Name = front.Name
+	}
+	return p, nil
+}
+
+// WriteMarkdownDir writes one Markdown file per prompt into dir, creating it if needed.
+// Namespaced prompts are written into subdirectories.
+func WriteMarkdownDir(dir string, prompts []Prompt, includeIDs bool) error {
+	if err := checkFileNames(prompts); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	for _, p := range prompts {
+		data, err := marshalMarkdown(p, includeIDs)
+		if err != nil {
+			return fmt.Errorf("error exporting prompt '%s': %w", p.Name, err)
+		}
+		if err := writeMarkdownFile(dir, promptFileName(p.Name), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMarkdownFile writes a single prompt as a Markdown file.
+func WriteMarkdownFile(path string, p Prompt, includeID bool) error {
+	data, err := marshalMarkdown(p, includeID)
+	if err != nil {
+		return fmt.Errorf("error exporting prompt '%s': %w", p.Name, err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	return nil
+}
+
+// ReadMarkdown reads a single Markdown file, or every prompt in a Markdown directory.
+func ReadMarkdown(path string) ([]Prompt, error) {
+	if !IsMarkdownFile(path) {
+		return ReadMarkdownDir(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	p, err := unmarshalMarkdown(data, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filepath.Base(path), err)
+	}
+	return []Prompt{p}, nil
+}
+
+// ReadMarkdownDir reads every .md and .markdown file in dir and its namespace subdirectories, in path order.
+func ReadMarkdownDir(dir string) ([]Prompt, error) {
+	files, err := markdownFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var prompts []Prompt
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+		p, err := unmarshalMarkdown(data, promptNameFromFile(file))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		prompts = append(prompts, p)
+	}
+	return prompts, nil
+}
@@ -0,0 +1,213 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictPolicy decides what happens when an imported prompt's name already exists with different data.
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictRename    ConflictPolicy = "rename"
+	ConflictMergeTags ConflictPolicy = "merge-tags"
+	ConflictPrompt    ConflictPolicy = "prompt"
+)
+
+// ConflictPolicies lists the valid --on-conflict values in the order shown to users.
+var ConflictPolicies = []ConflictPolicy{ConflictSkip, ConflictOverwrite, ConflictRename, ConflictMergeTags, ConflictPrompt}
+
+// ParseConflictPolicy converts a flag value into a ConflictPolicy.
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	for _, policy := range ConflictPolicies {
+		if string(policy) == value {
+			return policy, nil
+		}
+	}
+	names := make([]string, len(ConflictPolicies))
+	for i, policy := range ConflictPolicies {
+		names[i] = string(policy)
+	}
+	return "", invalidf("invalid conflict policy '%s', must be one of: %s", value, strings.Join(names, ", "))
+}
+
+// Import plan statuses describe how an incoming prompt compares to the library.
+const (
+	ImportNew       = "new"       // no prompt with this name exists
+	ImportIdentical = "identical" // same content, tags and description
+	ImportChanged   = "changed"   // same content, different tags or description
+	ImportConflict  = "conflict"  // different content
+	ImportInvalid   = "invalid"   // rejected before reaching the store
+)
+
+// ImportPlanEntry records what an import did, or would do, with one incoming prompt.
+type ImportPlanEntry struct {
+	Index  int // position of the record in the import file
+	Name   string
+	Status string
+	Action string
+	Reason string // why an invalid record was rejected
+}
+
+// Skipped reports whether the entry left the library untouched.
+func (e ImportPlanEntry) Skipped() bool {
+	return strings.HasPrefix(e.Action, "skip") || e.Action == "ask"
+}
+
+// ImportOptions controls how ImportPrompts treats existing prompts.
+type ImportOptions struct {
+	OnConflict ConflictPolicy
+	DryRun     bool
+	// Strict aborts the whole import on the first invalid record instead of skipping it.
+	Strict bool
+	// Resolve picks a policy for a single prompt when OnConflict is ConflictPrompt.
+	Resolve func(existing *Prompt, incoming Prompt) (ConflictPolicy, error)
+}
+
+// ImportPrompts imports prompts in a single transaction and returns the per-prompt plan.
+// With DryRun set the transaction is rolled back, so the plan reflects what would happen.
+func (a *App) ImportPrompts(prompts []Prompt, opts ImportOptions) ([]ImportPlanEntry, error) {
+	tx, err := a.promptStore.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	plan := make([]ImportPlanEntry, 0, len(prompts))
+	for i, incoming := range prompts {
+		// Imported records go through the same checks as prompts added interactively
+		if err := validateImportedPrompt(&incoming); err != nil {
+			if opts.Strict {
+				return nil, fmt.Errorf("invalid record %d ('%s'): %w", i, incoming.Name, err)
+			}
+			plan = append(plan, ImportPlanEntry{Index: i, Name: incoming.Name, Status: ImportInvalid, Action: "skip", Reason: err.Error()})
+			continue
+		}
+
+		entry, err := importOne(tx, incoming, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error importing prompt '%s': %w", incoming.Name, err)
+		}
+		entry.Index = i
+		plan = append(plan, entry)
+	}
+
+	if opts.DryRun {
+		return plan, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// validateImportedPrompt validates an incoming prompt like App.SavePrompt does and normalizes its tags and description.
+func validateImportedPrompt(p *Prompt) error {
+	if err := ValidatePromptName(p.Name); err != nil {
+		return err
+	}
+	if err := ValidatePromptContent(p.Prompt); err != nil {
+		return err
+	}
+	p.Description = strings.TrimSpace(p.Description)
+	if err := ValidatePromptDescription(p.Description); err != nil {
+		return err
+	}
+	p.Tags = NormalizeTags(p.Tags)
+	return nil
+}
+
+// addImportedPrompt stores p under name, with its description.
+func addImportedPrompt(tx *SQLitePromptStore, name string, p Prompt) error {
+	if err := tx.AddPrompt(name, p.Prompt, p.Tags); err != nil {
+		return err
+	}
+	if p.Description == "" {
+		return nil
+	}
+	return tx.SetDescription(name, p.Description)
+}
+
+// updateImportedPrompt replaces the content, tags and description of the prompt named p.Name.
+func updateImportedPrompt(tx *SQLitePromptStore, p Prompt) error {
+	if err := tx.UpdatePrompt(p.Name, p.Prompt, p.Tags); err != nil {
+		return err
+	}
+	return tx.SetDescription(p.Name, p.Description)
+}
+
+// importOne classifies a single incoming prompt and applies the conflict policy to it.
+func importOne(tx *SQLitePromptStore, incoming Prompt, opts ImportOptions) (ImportPlanEntry, error) {
+	entry := ImportPlanEntry{Name: incoming.Name}
+
+	existing, err := tx.FindPromptByName(incoming.Name)
+	if err != nil {
+		return entry, err
+	}
+
+	switch {
+	case existing == nil:
+		entry.Status = ImportNew
+		entry.Action = "add"
+		return entry, addImportedPrompt(tx, incoming.Name, incoming)
+	case existing.Prompt == incoming.Prompt && existing.Tags == incoming.Tags && existing.Description == incoming.Description:
+		entry.Status = ImportIdentical
+		entry.Action = "skip"
+		return entry, nil
+	case existing.Prompt == incoming.Prompt:
+		entry.Status = ImportChanged
+	default:
+		entry.Status = ImportConflict
+	}
+
+	policy := opts.OnConflict
+	if policy == ConflictPrompt {
+		if opts.DryRun || opts.Resolve == nil {
+			entry.Action = "ask"
+			return entry, nil
+		}
+		if policy, err = opts.Resolve(existing, incoming); err != nil {
+			return entry, err
+		}
+	}
+
+	switch policy {
+	case ConflictOverwrite:
+		entry.Action = "overwrite"
+		return entry, updateImportedPrompt(tx, incoming)
+	case ConflictRename:
+		newName, err := freePromptName(tx, incoming.Name)
+		if err != nil {
+			return entry, err
+		}
+		entry.Action = fmt.Sprintf("rename to '%s'", newName)
+		return entry, addImportedPrompt(tx, newName, incoming)
+	case ConflictMergeTags:
+		mergedTags := NormalizeTags(existing.Tags + "," + incoming.Tags)
+		if mergedTags == existing.Tags {
+			entry.Action = "skip"
+			return entry, nil
+		}
+		entry.Action = "merge tags"
+		return entry, tx.UpdatePrompt(existing.Name, existing.Prompt, mergedTags)
+	default:
+		entry.Action = "skip"
+		return entry, nil
+	}
+}
+
+// freePromptName returns the first of name-2, name-3, ... not already in the store.
+func freePromptName(store *SQLitePromptStore, name string) (string, error) {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", name, n)
+		existing, err := store.FindPromptByName(candidate)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+	}
+}
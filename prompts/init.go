@@ -0,0 +1,185 @@
+package prompts
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	appName    = "p"
+	dbFileName = "prompts.db"
+
+	// currentSchemaVersion is the highest migration this build knows how to apply.
+	currentSchemaVersion = 7
+)
+
+// InitDB opens the default database in the user's config directory.
+func InitDB() (*sql.DB, error) {
+	db, _, err := InitDBWithPath()
+	return db, err
+}
+
+// InitDBWithPath opens the default database in the user's config directory and returns its path.
+func InitDBWithPath() (*sql.DB, string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, "", fmt.Errorf("error finding user config directory: %w", err)
+	}
+
+	appConfigDir := filepath.Join(configDir, appName)
+	// Uses 0700 permissions for the config directory as prompts may contain sensitive data.
+	if err := os.MkdirAll(appConfigDir, 0o700); err != nil {
+		return nil, "", fmt.Errorf("error creating application config directory: %w", err)
+	}
+
+	dbPath := filepath.Join(appConfigDir, dbFileName)
+
+	db, err := OpenDB(dbPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return db, dbPath, nil
+}
+
+// busyTimeoutMillis is how long a connection waits for another process's write
+// lock, such as p serve handling a request while the CLI edits, before failing.
+const busyTimeoutMillis = 10000
+
+// OpenDB opens the SQLite database at dbPath and brings its schema up to date.
+func OpenDB(dbPath string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_busy_timeout=%d", dbPath, busyTimeoutMillis))
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	// Run database migrations
+	if err := runMigrations(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error running migrations: %w", err)
+	}
+	return db, nil
+}
+
+// runMigrations applies database schema migrations
+func runMigrations(db *sql.DB) error {
+	// Create migrations table if it doesn't exist
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating migrations table: %w", err)
+	}
+
+	// Migration 1: Create prompts table
+	if err := applyMigration(db, 1, `
+		CREATE TABLE IF NOT EXISTS prompts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			prompt TEXT NOT NULL,
+			tags TEXT
+		);
+	`); err != nil {
+		return fmt.Errorf("error applying migration 1: %w", err)
+	}
+
+	// Migration 2: Add created_at and updated_at columns.
+	// SQLite rejects non-constant defaults in ALTER TABLE, so existing rows are backfilled instead.
+	if err := applyMigration(db, 2, `
+		ALTER TABLE prompts ADD COLUMN created_at DATETIME;
+		ALTER TABLE prompts ADD COLUMN updated_at DATETIME;
+		UPDATE prompts SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
+	`); err != nil {
+		return fmt.Errorf("error applying migration 2: %w", err)
+	}
+
+	// Migration 3: Create settings table for persistent configuration
+	if err := applyMigration(db, 3, `
+		CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		);
+	`); err != nil {
+		return fmt.Errorf("error applying migration 3: %w", err)
+	}
+
+	// Migration 4: Track a hash of the last synced version of each prompt per sync directory
+	if err := applyMigration(db, 4, `
+		CREATE TABLE IF NOT EXISTS dir_sync_state (
+			dir TEXT NOT NULL,
+			name TEXT NOT NULL,
+			hash TEXT NOT NULL,
+			PRIMARY KEY (dir, name)
+		);
+	`); err != nil {
+		return fmt.Errorf("error applying migration 4: %w", err)
+	}
+
+	// Migration 5: Add an optional description to prompts
+	if err := applyMigration(db, 5, `
+		ALTER TABLE prompts ADD COLUMN description TEXT;
+	`); err != nil {
+		return fmt.Errorf("error applying migration 5: %w", err)
+	}
+
+	// Migration 6: Record when prompts are used, for frecency ranking
+	if err := applyMigration(db, 6, `
+		CREATE TABLE IF NOT EXISTS usage_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			prompt_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			used_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_usage_events_prompt ON usage_events(prompt_id);
+		CREATE TRIGGER IF NOT EXISTS delete_usage_events AFTER DELETE ON prompts
+		BEGIN
+			DELETE FROM usage_events WHERE prompt_id = OLD.id;
+		END;
+	`); err != nil {
+		return fmt.Errorf("error applying migration 6: %w", err)
+	}
+
+	// Migration 7: Let prompts be pinned to the top of rankings
+	if err := applyMigration(db, 7, `
+		ALTER TABLE prompts ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;
+	`); err != nil {
+		return fmt.Errorf("error applying migration 7: %w", err)
+	}
+
+	return nil
+}
+
+// applyMigration applies a single migration if it hasn't been applied yet
+func applyMigration(db *sql.DB, version int, sql string) error {
+	// Check if migration has already been applied
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = ?", version).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("error checking migration status: %w", err)
+	}
+
+	if count > 0 {
+		// Migration already applied
+		return nil
+	}
+
+	// Apply the migration
+	_, err = db.Exec(sql)
+	if err != nil {
+		return fmt.Errorf("error executing migration SQL: %w", err)
+	}
+
+	// Record the migration as applied
+	_, err = db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version)
+	if err != nil {
+		return fmt.Errorf("error recording migration: %w", err)
+	}
+
+	return nil
+}
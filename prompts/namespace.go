@@ -0,0 +1,171 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NamespaceSep separates the segments of a namespaced prompt name, as in code/review/go.
+// A namespace is written with a trailing separator, as in code/.
+const NamespaceSep = "/"
+
+// IsNamespace reports whether s names a namespace rather than a prompt.
+func IsNamespace(s string) bool {
+	return strings.HasSuffix(s, NamespaceSep)
+}
+
+// validateNameSegments rejects names with empty namespace segments.
+func validateNameSegments(name string) error {
+	for _, segment := range strings.Split(name, NamespaceSep) {
+		if segment == "" {
+			return invalidf("prompt name '%s' has an empty namespace segment", name)
+		}
+	}
+	return nil
+}
+
+// ListNamespace returns the prompts under a namespace such as code/, ordered by name.
+func (a *App) ListNamespace(namespace string) ([]Prompt, error) {
+	if !IsNamespace(namespace) {
+		namespace += NamespaceSep
+	}
+	return a.promptStore.ListPromptsWhere("substr(name, 1, length(?)) = ?", namespace, namespace)
+}
+
+// NameNode is a namespace segment in a tree of prompt names. A node can be a
+// prompt, a namespace, or both when code and code/review both exist.
+type NameNode struct {
+	Segment  string
+	Prompt   *Prompt // nil for a namespace that is not itself a prompt
+	Children []*NameNode
+}
+
+// NameTree arranges prompts into a tree by namespace, keeping the order of list within each level.
+func NameTree(list []Prompt) *NameNode {
+	root := &NameNode{}
+	for i := range list {
+		node := root
+		for _, segment := range strings.Split(list[i].Name, NamespaceSep) {
+			var child *NameNode
+			for _, c := range node.Children {
+				if c.Segment == segment {
+					child = c
+					break
+				}
+			}
+			if child == nil {
+				child = &NameNode{Segment: segment}
+				node.Children = append(node.Children, child)
+			}
+			node = child
+		}
+		node.Prompt = &list[i]
+	}
+	return root
+}
+
+// CompleteNameSegment completes a prompt name one namespace segment at a time:
+// names below the next separator collapse into their namespace, such as code/.
+// The order of names is kept.
+func CompleteNameSegment(names []string, toComplete string) []string {
+	seen := make(map[string]bool)
+	var completions []string
+	for _, name := range names {
+		rest, ok := strings.CutPrefix(name, toComplete)
+		if !ok {
+			continue
+		}
+		completion := name
+		if i := strings.Index(rest, NamespaceSep); i >= 0 {
+			completion = toComplete + rest[:i+1]
+		}
+		if !seen[completion] {
+			seen[completion] = true
+			completions = append(completions, completion)
+		}
+	}
+	return completions
+}
+
+// Move is a prompt renamed by MovePrompts.
+type Move struct {
+	From string
+	To   string
+}
+
+// MovePrompts renames a prompt, or every prompt under a namespace. Moving a
+// namespace such as code/ to eng/code/ keeps the rest of each name; moving a
+// prompt into a namespace such as eng/ keeps its last segment. Nothing is moved
+// if any new name is invalid or already taken.
+func (a *App) MovePrompts(from, to string) ([]Move, error) {
+	var moves []Move
+	if IsNamespace(from) {
+		if !IsNamespace(to) {
+			return nil, invalidf("cannot move namespace '%s' to a prompt name; end '%s' with %s", from, to, NamespaceSep)
+		}
+		list, err := a.ListNamespace(from)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("namespace '%s' %w", from, ErrNotFound)
+		}
+		for _, p := range list {
+			moves = append(moves, Move{From: p.Name, To: to + strings.TrimPrefix(p.Name, from)})
+		}
+	} else {
+		if _, err := a.GetPrompt(from); err != nil {
+			return nil, err
+		}
+		newName := to
+		if IsNamespace(to) {
+			newName = to + from[strings.LastIndex(from, NamespaceSep)+1:]
+		}
+		moves = []Move{{From: from, To: newName}}
+	}
+
+	moving := make(map[string]bool, len(moves))
+	for _, m := range moves {
+		moving[m.From] = true
+	}
+	for _, m := range moves {
+		if err := ValidatePromptName(m.To); err != nil {
+			return nil, err
+		}
+		existing, err := a.FindPrompt(m.To)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && !moving[m.To] {
+			return nil, fmt.Errorf("prompt name '%s' %w", m.To, ErrDuplicate)
+		}
+	}
+
+	tx, err := a.promptStore.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// Park every prompt under a temporary name first, so moving code/ into
+	// code/old/ cannot collide with a name that is about to be freed
+	for i, m := range moves {
+		if err := tx.RenamePrompt(m.From, movingName(i)); err != nil {
+			return nil, err
+		}
+	}
+	for i, m := range moves {
+		if err := tx.RenamePrompt(movingName(i), m.To); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return moves, nil
+}
+
+// movingName is the temporary name of the i-th prompt being moved. The NUL
+// byte keeps it clear of any name a user would give a prompt.
+func movingName(i int) string {
+	return fmt.Sprintf("\x00moving/%d", i)
+}
@@ -0,0 +1,79 @@
+package prompts
+
+import "strings"
+
+// settingNormalize stores the Normalization applied to prompt content when it is saved.
+const settingNormalize = "content.normalize"
+
+// Normalization is a policy for cleaning up whitespace in prompt content before it is saved.
+// Content is otherwise stored byte-exact.
+type Normalization string
+
+const (
+	// NormalizeNone stores content exactly as given.
+	NormalizeNone Normalization = "none"
+	// NormalizeTrailingNewline drops the single final newline that editors and echo add.
+	NormalizeTrailingNewline Normalization = "trailing-newline"
+	// NormalizeFull trims all leading and trailing whitespace.
+	NormalizeFull Normalization = "full"
+)
+
+// DefaultNormalization is used until another policy is configured, so content
+// is stored byte-exact unless trimming is opted into with p config normalize.
+const DefaultNormalization = NormalizeNone
+
+// Normalizations lists the valid policies.
+var Normalizations = []Normalization{NormalizeNone, NormalizeTrailingNewline, NormalizeFull}
+
+// ParseNormalization validates a policy name.
+func ParseNormalization(s string) (Normalization, error) {
+	for _, n := range Normalizations {
+		if string(n) == s {
+			return n, nil
+		}
+	}
+	return "", invalidf("unknown normalization '%s' (use none, trailing-newline, or full)", s)
+}
+
+// Apply returns content normalized according to n.
+func (n Normalization) Apply(content string) string {
+	switch n {
+	case NormalizeTrailingNewline:
+		if trimmed, ok := strings.CutSuffix(content, "\r\n"); ok {
+			return trimmed
+		}
+		return strings.TrimSuffix(content, "\n")
+	case NormalizeFull:
+		return strings.TrimSpace(content)
+	}
+	return content
+}
+
+// Normalization returns the configured content normalization policy.
+func (a *App) Normalization() (Normalization, error) {
+	value, err := a.promptStore.GetSetting(settingNormalize)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return DefaultNormalization, nil
+	}
+	return ParseNormalization(value)
+}
+
+// SetNormalization changes the policy applied to content saved from now on.
+func (a *App) SetNormalization(n Normalization) error {
+	if _, err := ParseNormalization(string(n)); err != nil {
+		return err
+	}
+	return a.promptStore.SetSetting(settingNormalize, string(n))
+}
+
+// NormalizeContent applies the configured normalization policy to content.
+func (a *App) NormalizeContent(content string) (string, error) {
+	n, err := a.Normalization()
+	if err != nil {
+		return "", err
+	}
+	return n.Apply(content), nil
+}
@@ -0,0 +1,418 @@
+// Package prompts is the core of p: a SQLite-backed prompt store, the App
+// service used by the CLI, import/export formats, sync, and template rendering.
+// Nothing in this package prints; results and errors are returned to the caller.
+package prompts
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MaxPromptNameLen        = 255
+	MaxPromptContentLen     = 10000
+	MaxPromptDescriptionLen = 500
+)
+
+// toTagSet splits, trims, and deduplicates tags into a set.
+func toTagSet(tags string) map[string]struct{} {
+	tagSet := make(map[string]struct{})
+	for _, tag := range strings.Split(tags, ",") {
+		if trimmed := strings.TrimSpace(tag); trimmed != "" {
+			tagSet[trimmed] = struct{}{}
+		}
+	}
+	return tagSet
+}
+
+// NormalizeTags deduplicates and sorts comma-separated tags, trimming whitespace.
+func NormalizeTags(tags string) string {
+	if tags == "" {
+		return ""
+	}
+	tagSet := toTagSet(tags)
+	var result []string
+	for tag := range tagSet {
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return strings.Join(result, ",")
+}
+
+// ValidatePromptName checks if prompt name meets basic requirements.
+func ValidatePromptName(name string) error {
+	if name == "" {
+		return invalidf("prompt name cannot be empty")
+	}
+	if len(name) > MaxPromptNameLen {
+		return invalidf("prompt name too long (%d chars), maximum %d characters", len(name), MaxPromptNameLen)
+	}
+	return validateNameSegments(name)
+}
+
+// ValidatePromptDescription checks that an optional description fits the length limit.
+func ValidatePromptDescription(description string) error {
+	if len(description) > MaxPromptDescriptionLen {
+		return invalidf("prompt description too long (%d chars), maximum %d characters", len(description), MaxPromptDescriptionLen)
+	}
+	return nil
+}
+
+// ValidatePromptContent checks if prompt content meets basic requirements.
+// Whitespace-only content counts as empty; the length limit applies to the content as stored.
+func ValidatePromptContent(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return invalidf("prompt content cannot be empty")
+	}
+	if len(content) > MaxPromptContentLen {
+		return invalidf("prompt content too long (%d chars), maximum %d characters", len(content), MaxPromptContentLen)
+	}
+	return nil
+}
+
+// App manages prompt-related operations using a SQLite store.
+type App struct {
+	promptStore *SQLitePromptStore
+	dbPath      string
+}
+
+// NewApp creates a new App instance with the given prompt store and database path.
+func NewApp(store *SQLitePromptStore, dbPath string) *App {
+	return &App{promptStore: store, dbPath: dbPath}
+}
+
+// Store returns the underlying prompt store.
+func (a *App) Store() *SQLitePromptStore {
+	return a.promptStore
+}
+
+// DBPath returns the path of the database file.
+func (a *App) DBPath() string {
+	return a.dbPath
+}
+
+// GetPrompt retrieves a prompt by name, failing if it does not exist.
+func (a *App) GetPrompt(name string) (*Prompt, error) {
+	return a.promptStore.GetPromptByName(name)
+}
+
+// FindPrompt retrieves a prompt by name, returning nil without an error if it does not exist.
+func (a *App) FindPrompt(name string) (*Prompt, error) {
+	return a.promptStore.FindPromptByName(name)
+}
+
+// CreatePrompt validates and stores a new prompt with the given content, returning the stored prompt.
+func (a *App) CreatePrompt(name, content, tags string) (*Prompt, error) {
+	if err := ValidatePromptName(name); err != nil {
+		return nil, err
+	}
+	content, err := a.NormalizeContent(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidatePromptContent(content); err != nil {
+		return nil, err
+	}
+	if err := a.promptStore.AddPrompt(name, content, NormalizeTags(tags)); err != nil {
+		return nil, err
+	}
+	return a.promptStore.GetPromptByName(name)
+}
+
+// DeletePrompt removes a prompt by name from the store.
+func (a *App) DeletePrompt(name string) error {
+	err := a.promptStore.DeletePrompt(name)
+	if err != nil {
+		return fmt.Errorf("error deleting prompt: %w", err)
+	}
+	return nil
+}
+
+// EditPrompt updates an existing prompt's content and tags.
+// It reports whether anything changed; an unchanged prompt is not written.
+func (a *App) EditPrompt(existingPrompt *Prompt, newPrompt, newTags string) (bool, error) {
+	if err := ValidatePromptName(existingPrompt.Name); err != nil {
+		return false, err
+	}
+
+	newPrompt, err := a.NormalizeContent(newPrompt)
+	if err != nil {
+		return false, err
+	}
+	normalizedTags := NormalizeTags(newTags)
+	if newPrompt == existingPrompt.Prompt && normalizedTags == existingPrompt.Tags {
+		return false, nil
+	}
+
+	if err := ValidatePromptContent(newPrompt); err != nil {
+		return false, err
+	}
+
+	err = a.promptStore.UpdatePrompt(existingPrompt.Name, newPrompt, normalizedTags)
+	if err != nil {
+		return false, fmt.Errorf("error editing prompt: %w", err)
+	}
+	return true, nil
+}
+
+// SetDescription replaces the description of the named prompt.
+func (a *App) SetDescription(name, description string) error {
+	description = strings.TrimSpace(description)
+	if err := ValidatePromptDescription(description); err != nil {
+		return err
+	}
+	return a.promptStore.SetDescription(name, description)
+}
+
+// SetPinned pins a prompt so it is ranked first, or unpins it.
+func (a *App) SetPinned(name string, pinned bool) error {
+	return a.promptStore.SetPinned(name, pinned)
+}
+
+// RenamePrompt gives a prompt a new name. It returns ErrDuplicate if the name is taken.
+func (a *App) RenamePrompt(oldName, newName string) error {
+	if err := ValidatePromptName(newName); err != nil {
+		return err
+	}
+	return a.promptStore.RenamePrompt(oldName, newName)
+}
+
+// PromptFields are the fields of a prompt that a form edits together.
+type PromptFields struct {
+	Name        string
+	Tags        string
+	Description string
+	Body        string
+}
+
+// SavePrompt creates a prompt from fields, or updates the prompt named existing,
+// renaming it if the name changed. Every field is validated first and written in
+// one transaction, so a failed save leaves the prompt as it was.
+func (a *App) SavePrompt(existing string, fields PromptFields) (*Prompt, error) {
+	if err := ValidatePromptName(fields.Name); err != nil {
+		return nil, err
+	}
+	body, err := a.NormalizeContent(fields.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidatePromptContent(body); err != nil {
+		return nil, err
+	}
+	description := strings.TrimSpace(fields.Description)
+	if err := ValidatePromptDescription(description); err != nil {
+		return nil, err
+	}
+	tags := NormalizeTags(fields.Tags)
+
+	tx, err := a.promptStore.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if existing == "" {
+		err = tx.AddPrompt(fields.Name, body, tags)
+	} else {
+		if fields.Name != existing {
+			if err := tx.RenamePrompt(existing, fields.Name); err != nil {
+				return nil, err
+			}
+		}
+		err = tx.UpdatePrompt(fields.Name, body, tags)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing != "" || description != "" {
+		if err := tx.SetDescription(fields.Name, description); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return a.promptStore.GetPromptByName(fields.Name)
+}
+
+// ListPrompts retrieves all prompts, optionally filtered by tags.
+// Supports AND/OR logic: "tag1,tag2" (OR) or "AND:tag1,tag2" (AND)
+func (a *App) ListPrompts(tagsFilter string) ([]Prompt, error) {
+	if tagsFilter != "" {
+		return a.promptStore.ListPromptsByTags(tagsFilter)
+	}
+	return a.promptStore.ListPrompts()
+}
+
+// PromptNames returns the names of all prompts in RankPrompts order.
+func (a *App) PromptNames() ([]string, error) {
+	prompts, err := a.ListPrompts("")
+	if err != nil {
+		return nil, err
+	}
+	if err := a.RankPrompts(prompts); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(prompts))
+	for i, p := range prompts {
+		names[i] = p.Name
+	}
+	return names, nil
+}
+
+// TagCount is a tag and the number of prompts carrying it.
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TagCounts returns every tag in use with its prompt count, sorted by tag.
+func (a *App) TagCounts() ([]TagCount, error) {
+	prompts, err := a.ListPrompts("")
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, p := range prompts {
+		for tag := range toTagSet(p.Tags) {
+			counts[tag]++
+		}
+	}
+	tags := make([]TagCount, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, TagCount{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// PromptFilter narrows a prompt listing. Zero-valued fields do not filter.
+type PromptFilter struct {
+	Tags      string   // same syntax as ListPrompts
+	Names     []string // exact names; unknown names are an error
+	Since     time.Time
+	Text      string // case-insensitive substring of name or content
+	Query     string // query language expression, see ParseQuery
+	Pinned    bool   // only pinned prompts
+	Namespace string // only prompts under this namespace, such as code/
+}
+
+// FilterPrompts retrieves the prompts matching every criterion in filter, with one
+// query whose WHERE clause ANDs the criteria together.
+func (a *App) FilterPrompts(filter PromptFilter) ([]Prompt, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, conditionArgs ...any) {
+		conditions = append(conditions, "("+condition+")")
+		args = append(args, conditionArgs...)
+	}
+
+	if len(filter.Names) > 0 {
+		names := make([]any, len(filter.Names))
+		for i, name := range filter.Names {
+			// Catch typos instead of silently exporting less than asked for
+			if _, err := a.promptStore.GetPromptByName(name); err != nil {
+				return nil, err
+			}
+			names[i] = name
+		}
+		where("name IN (?"+strings.Repeat(", ?", len(names)-1)+")", names...)
+	}
+	if condition, tagArgs := tagCondition(filter.Tags); condition != "" {
+		where(condition, tagArgs...)
+	}
+	if !filter.Since.IsZero() {
+		where("updated_at >= ?", filter.Since.UTC().Format(sqliteTimeLayout))
+	}
+	if filter.Pinned {
+		where("pinned = 1")
+	}
+	if filter.Namespace != "" {
+		where("substr(name, 1, length(?)) = ?", filter.Namespace, filter.Namespace)
+	}
+	if filter.Text != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Text) + "%"
+		where(`name LIKE ? ESCAPE '\' OR prompt LIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+	if filter.Query != "" {
+		condition, queryArgs, err := ParseQuery(filter.Query, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		where(condition, queryArgs...)
+	}
+
+	if len(conditions) == 0 {
+		return a.promptStore.ListPrompts()
+	}
+	return a.promptStore.ListPromptsWhere(strings.Join(conditions, " AND "), args...)
+}
+
+// tagCondition turns a tag filter in ListPrompts syntax, "a,b" for any tag or
+// "AND:a,b" for every tag, into an SQL condition. Tags are stored sorted and
+// comma-separated, so wrapping them in commas anchors an exact match on one tag.
+func tagCondition(tagsFilter string) (string, []any) {
+	joiner := " OR "
+	if rest, ok := strings.CutPrefix(tagsFilter, "AND:"); ok {
+		joiner, tagsFilter = " AND ", rest
+	}
+	var conditions []string
+	var args []any
+	for _, tag := range strings.Split(tagsFilter, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			conditions = append(conditions, "instr(',' || COALESCE(tags, '') || ',', ?) > 0")
+			args = append(args, ","+tag+",")
+		}
+	}
+	return strings.Join(conditions, joiner), args
+}
+
+// ParseSince parses a --since value: a date (2006-01-02), an RFC 3339 timestamp,
+// or a duration before now such as 36h or 7d.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, invalidf("invalid --since value '%s', use a date (2006-01-02), RFC 3339 time, or duration (36h, 7d)", value)
+}
+
+// BackupDatabase copies the database file to backupPath.
+func (a *App) BackupDatabase(backupPath string) error {
+	if err := copyFile(a.dbPath, backupPath); err != nil {
+		return fmt.Errorf("error copying database: %w", err)
+	}
+	return nil
+}
+
+// RestoreDatabase replaces the live database with a verified backup.
+// It closes the connection, validates the backup, snapshots the current database,
+// and swaps the files with a rename. It returns the path of the snapshot.
+func (a *App) RestoreDatabase(backupPath string) (string, error) {
+	if err := a.promptStore.Close(); err != nil {
+		return "", fmt.Errorf("error closing database: %w", err)
+	}
+	if _, err := VerifyBackup(backupPath); err != nil {
+		return "", err
+	}
+	snapshotPath, err := snapshotDatabase(a.dbPath)
+	if err != nil {
+		return "", err
+	}
+	if err := replaceDatabase(a.dbPath, backupPath); err != nil {
+		return "", err
+	}
+	return snapshotPath, nil
+}
@@ -0,0 +1,1640 @@
+package prompts
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidatePromptName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"empty name", "", true},
+		{"valid name", "test", false},
+		{"max length name", string(make([]byte, 255)), false},
+		{"too long name", string(make([]byte, 256)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePromptName(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePromptName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePromptContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"empty content", "", true},
+		{"whitespace only", "   \n\t  ", true},
+		{"valid content", "test prompt", false},
+		{"max length content", string(make([]byte, 10000)), false},
+		{"too long content", string(make([]byte, 10001)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePromptContent(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePromptContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"empty tags", "", ""},
+		{"single tag", "test", "test"},
+		{"multiple tags", "b,a,c", "a,b,c"},
+		{"duplicate tags", "test,test,other", "other,test"},
+		{"whitespace tags", " a , b , c ", "a,b,c"},
+		{"malformed tags", "a,,b,,,c", "a,b,c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NormalizeTags(tt.input)
+			if result != tt.expected {
+				t.Errorf("NormalizeTags() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func setupTestDB(t *testing.T) (*SQLitePromptStore, string) {
+	tmpFile, err := os.CreateTemp("", "test_*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	db, err := sql.Open("sqlite3", tmpFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSQLitePromptStore(db)
+	t.Cleanup(func() { db.Close() })
+	return store, tmpFile.Name()
+}
+
+func TestAppWithEmptyDB(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+
+	// Test listing empty DB
+	prompts, err := app.ListPrompts("")
+	if err != nil {
+		t.Errorf("ListPrompts() on empty DB failed: %v", err)
+	}
+	if len(prompts) != 0 {
+		t.Errorf("Expected 0 prompts, got %d", len(prompts))
+	}
+}
+
+func TestDuplicateNames(t *testing.T) {
+	store, _ := setupTestDB(t)
+
+	// Add first prompt
+	err := store.AddPrompt("test", "content1", "tag1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Try to add duplicate name - should fail due to UNIQUE constraint
+	err = store.AddPrompt("test", "content2", "tag2")
+	if !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for duplicate name, got %v", err)
+	}
+}
+
+func TestErrorClasses(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"get missing", func() error { _, err := app.GetPrompt("missing"); return err }(), ErrNotFound},
+		{"delete missing", app.DeletePrompt("missing"), ErrNotFound},
+		{"empty name", func() error { _, err := app.CreatePrompt("", "x", ""); return err }(), ErrInvalid},
+		{"empty content", func() error { _, err := app.CreatePrompt("x", " ", ""); return err }(), ErrInvalid},
+		{"bad format", func() error { _, err := DetectFormat("out.txt", "xml"); return err }(), ErrInvalid},
+		{"missing variable", func() error { _, err := Render("{{x}}", nil); return err }(), ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestEditorCancellation(t *testing.T) {
+	// Test that validation catches empty content
+	err := ValidatePromptContent("")
+	if err == nil {
+		t.Error("Expected error for empty content, got nil")
+	}
+
+	// Test that whitespace-only content is properly validated
+	err = ValidatePromptContent("   \n\t  ")
+	if err == nil {
+		t.Error("Expected error for whitespace-only content, got nil")
+	}
+}
+
+func TestListPromptsWithTags(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+
+	// Add test prompts
+	err := store.AddPrompt("test1", "content1", "tag1,tag2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.AddPrompt("test2", "content2", "tag2,tag3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test filtering by tags
+	prompts, err := app.ListPrompts("tag2")
+	if err != nil {
+		t.Errorf("ListPrompts with tags failed: %v", err)
+	}
+	if len(prompts) != 2 {
+		t.Errorf("Expected 2 prompts with tag2, got %d", len(prompts))
+	}
+}
+
+func TestDeletePromptIntegration(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+
+	// Add a test prompt
+	err := store.AddPrompt("testdelete", "test content", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Delete the prompt
+	err = app.DeletePrompt("testdelete")
+	if err != nil {
+		t.Errorf("DeletePrompt failed: %v", err)
+	}
+
+	// Verify prompt was deleted
+	_, err = store.GetPromptByName("testdelete")
+	if err == nil {
+		t.Error("Expected error when retrieving deleted prompt, got nil")
+	}
+}
+
+func TestCLIWorkflowIntegration(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+
+	// Test full workflow: add, list, edit, delete
+
+	// Add a prompt directly to store (since AddPrompt requires editor interaction)
+	err := store.AddPrompt("workflow-test", "initial content", "workflow,test")
+	if err != nil {
+		t.Fatalf("Failed to add prompt: %v", err)
+	}
+
+	// List all prompts
+	prompts, err := app.ListPrompts("")
+	if err != nil {
+		t.Fatalf("Failed to list prompts: %v", err)
+	}
+	if len(prompts) != 1 {
+		t.Errorf("Expected 1 prompt, got %d", len(prompts))
+	}
+
+	// List with tag filter (OR logic)
+	prompts, err = app.ListPrompts("workflow")
+	if err != nil {
+		t.Fatalf("Failed to list prompts with tag filter: %v", err)
+	}
+	if len(prompts) != 1 {
+		t.Errorf("Expected 1 prompt with workflow tag, got %d", len(prompts))
+	}
+
+	// List with AND logic
+	prompts, err = app.ListPrompts("AND:workflow,test")
+	if err != nil {
+		t.Fatalf("Failed to list prompts with AND logic: %v", err)
+	}
+	if len(prompts) != 1 {
+		t.Errorf("Expected 1 prompt with both tags, got %d", len(prompts))
+	}
+
+	// Edit the prompt
+	_, err = app.EditPrompt(&prompts[0], "updated content", "workflow,updated")
+	if err != nil {
+		t.Fatalf("Failed to edit prompt: %v", err)
+	}
+
+	// Verify edit
+	updatedPrompt, err := store.GetPromptByName("workflow-test")
+	if err != nil {
+		t.Fatalf("Failed to get updated prompt: %v", err)
+	}
+	if updatedPrompt.Prompt != "updated content" {
+		t.Errorf("Expected updated content, got %s", updatedPrompt.Prompt)
+	}
+	if updatedPrompt.Tags != "updated,workflow" { // normalized order
+		t.Errorf("Expected updated tags, got %s", updatedPrompt.Tags)
+	}
+
+	// Delete the prompt
+	err = app.DeletePrompt("workflow-test")
+	if err != nil {
+		t.Fatalf("Failed to delete prompt: %v", err)
+	}
+
+	// Verify deletion
+	_, err = store.GetPromptByName("workflow-test")
+	if err == nil {
+		t.Error("Expected error when retrieving deleted prompt, got nil")
+	}
+}
+
+// setupMigratedDB creates a database file with the full migrated schema.
+func setupMigratedDB(t *testing.T, path string) *SQLitePromptStore {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewSQLitePromptStore(db)
+}
+
+func TestRestoreDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "prompts.db")
+	backupPath := filepath.Join(dir, "backup.db")
+
+	backupStore := setupMigratedDB(t, backupPath)
+	if err := backupStore.AddPrompt("from-backup", "backup content", ""); err != nil {
+		t.Fatal(err)
+	}
+	backupStore.Close()
+
+	store := setupMigratedDB(t, dbPath)
+	if err := store.AddPrompt("current", "current content", ""); err != nil {
+		t.Fatal(err)
+	}
+	app := NewApp(store, dbPath)
+
+	snapshotPath, err := app.RestoreDatabase(backupPath)
+	if err != nil {
+		t.Fatalf("RestoreDatabase failed: %v", err)
+	}
+
+	restored := setupMigratedDB(t, dbPath)
+	if _, err := restored.GetPromptByName("from-backup"); err != nil {
+		t.Errorf("Expected restored prompt, got error: %v", err)
+	}
+	if _, err := restored.GetPromptByName("current"); err == nil {
+		t.Error("Expected current prompt to be replaced by restore")
+	}
+
+	snapshot, err := InspectDatabase(snapshotPath)
+	if err != nil {
+		t.Fatalf("Failed to inspect snapshot: %v", err)
+	}
+	if snapshot.PromptCount != 1 {
+		t.Errorf("Expected 1 prompt in snapshot, got %d", snapshot.PromptCount)
+	}
+}
+
+func TestVerifyBackupRejectsNonSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyBackup(path); err == nil {
+		t.Error("Expected error for non-SQLite backup, got nil")
+	}
+}
+
+func TestImportPromptsConflictPolicies(t *testing.T) {
+	tests := []struct {
+		policy      ConflictPolicy
+		wantContent string
+		wantTags    string
+		wantRenamed bool
+	}{
+		{ConflictSkip, "local", "a", false},
+		{ConflictOverwrite, "remote", "b", false},
+		{ConflictRename, "local", "a", true},
+		{ConflictMergeTags, "local", "a,b", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			store, dbPath := setupTestDB(t)
+			app := NewApp(store, dbPath)
+			if err := store.AddPrompt("shared", "local", "a"); err != nil {
+				t.Fatal(err)
+			}
+
+			plan, err := app.ImportPrompts([]Prompt{
+				{Name: "shared", Prompt: "remote", Tags: "b"},
+				{Name: "fresh", Prompt: "new content", Tags: "", Description: "  Brand new "},
+			}, ImportOptions{OnConflict: tt.policy})
+			if err != nil {
+				t.Fatalf("ImportPrompts failed: %v", err)
+			}
+			if plan[0].Status != ImportConflict || plan[1].Status != ImportNew {
+				t.Errorf("Unexpected plan statuses: %+v", plan)
+			}
+
+			got, err := store.GetPromptByName("shared")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Prompt != tt.wantContent || got.Tags != tt.wantTags {
+				t.Errorf("Expected %q/%q, got %q/%q", tt.wantContent, tt.wantTags, got.Prompt, got.Tags)
+			}
+			if _, err := store.GetPromptByName("shared-2"); (err == nil) != tt.wantRenamed {
+				t.Errorf("Renamed prompt present = %v, want %v", err == nil, tt.wantRenamed)
+			}
+			if fresh, err := store.GetPromptByName("fresh"); err != nil || fresh.Description != "Brand new" {
+				t.Errorf("Expected the imported description, got %+v, %v", fresh, err)
+			}
+		})
+	}
+}
+
+func TestImportPromptsDryRun(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+	if err := store.AddPrompt("same", "content", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := app.ImportPrompts([]Prompt{
+		{Name: "same", Prompt: "content", Tags: "x"},
+		{Name: "same", Prompt: "content", Tags: "y"},
+		{Name: "added", Prompt: "content", Tags: ""},
+	}, ImportOptions{OnConflict: ConflictOverwrite, DryRun: true})
+	if err != nil {
+		t.Fatalf("ImportPrompts failed: %v", err)
+	}
+
+	wantStatuses := []string{ImportIdentical, ImportChanged, ImportNew}
+	for i, want := range wantStatuses {
+		if plan[i].Status != want {
+			t.Errorf("plan[%d].Status = %s, want %s", i, plan[i].Status, want)
+		}
+	}
+
+	if _, err := store.GetPromptByName("added"); err == nil {
+		t.Error("Dry run should not add prompts")
+	}
+}
+
+func TestImportPromptsValidation(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+
+	records := []Prompt{
+		{Name: "ok", Prompt: "content", Tags: "b, a,b"},
+		{Name: "blank", Prompt: "   \n", Tags: ""},
+		{Name: "huge", Prompt: string(make([]byte, MaxPromptContentLen+1)), Tags: ""},
+	}
+
+	plan, err := app.ImportPrompts(records, ImportOptions{OnConflict: ConflictSkip})
+	if err != nil {
+		t.Fatalf("ImportPrompts failed: %v", err)
+	}
+	for _, i := range []int{1, 2} {
+		if plan[i].Status != ImportInvalid || plan[i].Index != i || plan[i].Reason == "" {
+			t.Errorf("Expected record %d to be rejected with a reason, got %+v", i, plan[i])
+		}
+	}
+	got, err := store.GetPromptByName("ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Tags != "a,b" {
+		t.Errorf("Expected normalized tags 'a,b', got '%s'", got.Tags)
+	}
+
+	// Strict mode aborts and leaves nothing behind
+	strictStore, strictPath := setupTestDB(t)
+	strictApp := NewApp(strictStore, strictPath)
+	if _, err := strictApp.ImportPrompts(records, ImportOptions{OnConflict: ConflictSkip, Strict: true}); err == nil {
+		t.Error("Expected strict import to fail on invalid record")
+	}
+	if _, err := strictStore.GetPromptByName("ok"); err == nil {
+		t.Error("Strict import should roll back valid records")
+	}
+}
+
+func TestExportImportFormatsRoundTrip(t *testing.T) {
+	prompts := []Prompt{
+		{ID: 7, Name: "review", Prompt: "Review this code:\n\n```go\n\tx := 1\n```\n", Tags: "code,go", Description: "Reviews Go: carefully"},
+		{ID: 9, Name: "email, formal", Prompt: "Write \"politely\"", Tags: ""},
+	}
+
+	for _, format := range []string{FormatJSON, FormatJSONL, FormatYAML, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WritePrompts(&buf, format, prompts, false); err != nil {
+				t.Fatalf("WritePrompts failed: %v", err)
+			}
+			got, err := ReadPrompts(&buf, format)
+			if err != nil {
+				t.Fatalf("ReadPrompts failed: %v", err)
+			}
+			if len(got) != len(prompts) {
+				t.Fatalf("Expected %d prompts, got %d", len(prompts), len(got))
+			}
+			for i := range prompts {
+				want := prompts[i]
+				want.ID = 0 // IDs are omitted by default
+				if got[i] != want {
+					t.Errorf("Round trip mismatch:\n got  %+v\n want %+v", got[i], want)
+				}
+			}
+		})
+	}
+
+	t.Run(FormatMarkdown, func(t *testing.T) {
+		dir := t.TempDir()
+		if err := WriteMarkdownDir(dir, prompts, true); err != nil {
+			t.Fatalf("WriteMarkdownDir failed: %v", err)
+		}
+		got, err := ReadMarkdownDir(dir)
+		if err != nil {
+			t.Fatalf("ReadMarkdownDir failed: %v", err)
+		}
+		if len(got) != 2 || got[0] != prompts[1] || got[1] != prompts[0] {
+			t.Errorf("Markdown round trip mismatch: %+v", got)
+		}
+
+		file := filepath.Join(t.TempDir(), "review.md")
+		if err := WriteMarkdownFile(file, prompts[0], true); err != nil {
+			t.Fatalf("WriteMarkdownFile failed: %v", err)
+		}
+		got, err = ReadMarkdown(file)
+		if err != nil || len(got) != 1 || got[0] != prompts[0] {
+			t.Errorf("Markdown file round trip = %+v, %v", got, err)
+		}
+	})
+}
+
+func TestPromptFileName(t *testing.T) {
+	for _, name := range []string{"review", "code/review", "code_review", "a:b*c?", `x\y"z<>|`, "100%", "%2F", ".hidden", "trailing.", "tab\there"} {
+		file := promptFileName(name)
+		for _, segment := range strings.Split(file, "/") {
+			if strings.ContainsAny(segment, `\:*?"<>|`+"\t") || strings.HasPrefix(segment, ".") {
+				t.Errorf("promptFileName(%q) = %q keeps an unsafe character", name, file)
+			}
+		}
+		if got := promptNameFromFile(file); got != name {
+			t.Errorf("promptNameFromFile(promptFileName(%q)) = %q", name, got)
+		}
+	}
+	if got := promptFileName("code/review"); got != "code/review.md" {
+		t.Errorf("Expected namespaces to become directories, got %q", got)
+	}
+	if got := promptNameFromFile("50%off.md"); got != "50%off" {
+		t.Errorf("Expected a stray %% to be kept, got %q", got)
+	}
+
+	dir := t.TempDir()
+	collide := []Prompt{{Name: "Review", Prompt: "a"}, {Name: "review", Prompt: "b"}}
+	if err := WriteMarkdownDir(dir, collide, false); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected names differing only in case to be rejected, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected nothing written after a rejected export, got %d files", len(entries))
+	}
+}
+
+func TestUnmarshalMarkdown(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    Prompt
+		wantErr bool
+	}{
+		{"---\nname: a\ntags: [x]\n---\nBody\n", Prompt{Name: "a", Tags: "x", Prompt: "Body\n"}, false},
+		{"---\r\nname: a\r\ntags: [x]\r\n---\r\nBody\r\n", Prompt{Name: "a", Tags: "x", Prompt: "Body\r\n"}, false},
+		{"---\r\n---\r\nBody", Prompt{Name: "file", Prompt: "Body"}, false},
+		{"No front matter\r\n---\r\n", Prompt{Name: "file", Prompt: "No front matter\r\n---\r\n"}, false},
+		{"---\r\nname: a\r\nBody", Prompt{}, true},
+	}
+	for _, tt := range tests {
+		got, err := unmarshalMarkdown([]byte(tt.data), "file")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unmarshalMarkdown(%q) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("unmarshalMarkdown(%q) = %+v, want %+v", tt.data, got, tt.want)
+		}
+	}
+
+	// Hand-written files may use the long extension
+	dir := t.TempDir()
+	for file, data := range map[string]string{"notes.markdown": "Long", "code/review.MD": "Upper", "skip.txt": "Not Markdown"} {
+		if err := writeMarkdownFile(dir, file, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := ReadMarkdownDir(dir)
+	if err != nil {
+		t.Fatalf("ReadMarkdownDir failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "code/review" || got[1].Name != "notes" || got[1].Prompt != "Long" {
+		t.Errorf("Expected code/review and notes, got %+v", got)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		path, explicit, want string
+	}{
+		{"out.json", "", FormatJSON},
+		{"out.jsonl", "", FormatJSONL},
+		{"out.yml", "", FormatYAML},
+		{"out.csv", "", FormatCSV},
+		{"-", "", FormatJSON},
+		{"-", "yaml", FormatYAML},
+		{t.TempDir(), "", FormatMarkdown},
+		{"review.md", "", FormatMarkdown},
+		{"notes.MD", "", FormatMarkdown},
+		{"new-dir/", "", FormatMarkdown},
+		{"new-dir" + string(filepath.Separator), "", FormatMarkdown},
+		{"new-dir/", "json", FormatJSON},
+	}
+	for _, tt := range tests {
+		got, err := DetectFormat(tt.path, tt.explicit)
+		if err != nil || got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) = %q, %v; want %q", tt.path, tt.explicit, got, err, tt.want)
+		}
+	}
+	if _, err := DetectFormat("x", "xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestReadSources(t *testing.T) {
+	tests := []struct {
+		source    string
+		path      string
+		wantNames []string
+		wantTag   string
+	}{
+		{SourceAwesomeChatGPT, "testdata/awesome-chatgpt-prompts.csv", []string{"linux-terminal", "english-translator-and-improver"}, "awesome-chatgpt-prompts"},
+		{SourceFabric, "testdata/fabric", []string{"extract_wisdom", "summarize"}, "fabric"},
+		{SourceChatML, "testdata/chatml.json", []string{"sql-helper", "chatml-2"}, "chatml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			prompts, err := ReadSource(tt.source, tt.path)
+			if err != nil {
+				t.Fatalf("ReadSource failed: %v", err)
+			}
+			if len(prompts) != len(tt.wantNames) {
+				t.Fatalf("Expected %d prompts, got %d", len(tt.wantNames), len(prompts))
+			}
+			for i, p := range prompts {
+				if p.Name != tt.wantNames[i] {
+					t.Errorf("prompts[%d].Name = %q, want %q", i, p.Name, tt.wantNames[i])
+				}
+				if p.Tags != tt.wantTag {
+					t.Errorf("prompts[%d].Tags = %q, want %q", i, p.Tags, tt.wantTag)
+				}
+				if err := ValidatePromptContent(p.Prompt); err != nil {
+					t.Errorf("prompts[%d] has invalid content: %v", i, err)
+				}
+			}
+		})
+	}
+
+	chat, err := ReadSource(SourceChatML, "testdata/chatml.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<|im_start|>system\nYou are a SQL expert.<|im_end|>\n<|im_start|>user\nExplain this query.<|im_end|>"
+	if chat[0].Prompt != want {
+		t.Errorf("Unexpected ChatML rendering:\n%s", chat[0].Prompt)
+	}
+	if chat[1].Prompt != "You translate text to French." {
+		t.Errorf("Expected single message content, got %q", chat[1].Prompt)
+	}
+}
+
+func TestFilterPrompts(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+	for _, p := range []Prompt{
+		{Name: "onboarding-setup", Prompt: "Set up your laptop", Tags: "onboarding"},
+		{Name: "onboarding-git", Prompt: "Clone the repos", Tags: "onboarding,git"},
+		{Name: "release", Prompt: "Cut a release", Tags: "git"},
+		{Name: "ops/deploy", Prompt: "Run deploy_prod", Tags: "github"},
+	} {
+		if err := store.AddPrompt(p.Name, p.Prompt, p.Tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.SetPinned("onboarding-git", true); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter PromptFilter
+		want   []string
+	}{
+		{"tags", PromptFilter{Tags: "onboarding"}, []string{"onboarding-git", "onboarding-setup"}},
+		{"and tags", PromptFilter{Tags: "AND:onboarding,git"}, []string{"onboarding-git"}},
+		{"names", PromptFilter{Names: []string{"release", "onboarding-git"}}, []string{"onboarding-git", "release"}},
+		{"text", PromptFilter{Text: "LAPTOP"}, []string{"onboarding-setup"}},
+		{"query", PromptFilter{Query: "tag:git NOT name:onboarding*"}, []string{"release"}},
+		{"query and tags", PromptFilter{Tags: "onboarding", Query: "LAPTOP"}, []string{"onboarding-setup"}},
+		{"since past", PromptFilter{Since: time.Now().Add(-time.Hour)}, []string{"onboarding-git", "onboarding-setup", "ops/deploy", "release"}},
+		{"since future", PromptFilter{Since: time.Now().Add(time.Hour)}, nil},
+		{"tag is exact", PromptFilter{Tags: "github"}, []string{"ops/deploy"}},
+		{"text underscore is literal", PromptFilter{Text: "y_p"}, []string{"ops/deploy"}},
+		{"namespace", PromptFilter{Namespace: "ops/"}, []string{"ops/deploy"}},
+		{"every filter", PromptFilter{
+			Tags: "git", Names: []string{"onboarding-git", "release"}, Since: time.Now().Add(-time.Hour),
+			Text: "clone", Query: "NOT tag:draft", Pinned: true,
+		}, []string{"onboarding-git"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompts, err := app.FilterPrompts(tt.filter)
+			if err != nil {
+				t.Fatalf("FilterPrompts failed: %v", err)
+			}
+			var got []string
+			for _, p := range prompts {
+				got = append(got, p.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := app.FilterPrompts(PromptFilter{Names: []string{"missing"}}); err == nil {
+		t.Error("Expected error for unknown name")
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+	for _, p := range []Prompt{
+		{Name: "go-review", Prompt: "Review this Go code", Tags: "code,go"},
+		{Name: "go-tests", Prompt: "Write a unit test for it", Tags: "go,testing"},
+		{Name: "go-draft", Prompt: "Unit test ideas", Tags: "draft,go"},
+		{Name: "email", Prompt: "Write a 100% polite_email", Tags: "writing"},
+	} {
+		if err := store.AddPrompt(p.Name, p.Prompt, p.Tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`tag:go AND (name:*review OR body:"unit test") NOT tag:draft`, []string{"go-review", "go-tests"}},
+		{`tag:go NOT tag:draft`, []string{"go-review", "go-tests"}},
+		{`tag:GO tag:test*`, []string{"go-tests"}},
+		{`tag:tes`, nil},
+		{`write OR review`, []string{"email", "go-review", "go-tests"}},
+		{`name:go`, []string{"go-draft", "go-review", "go-tests"}},
+		{`name:go*s`, []string{"go-tests"}},
+		{`body:"100%"`, []string{"email"}},
+		{`body:_`, []string{"email"}},
+		{`NOT (tag:go OR tag:writing)`, nil},
+		{`tag:writing OR tag:draft tag:go`, []string{"email", "go-draft"}},
+		{`updated:>2000-01-01`, []string{"email", "go-draft", "go-review", "go-tests"}},
+		{`updated:<7d`, nil},
+		{`created:2000-01-01`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			prompts, err := app.QueryPrompts(tt.query)
+			if err != nil {
+				t.Fatalf("QueryPrompts failed: %v", err)
+			}
+			var got []string
+			for _, p := range prompts {
+				got = append(got, p.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		query string
+		pos   int
+	}{
+		{``, 1},
+		{`tag:go AND`, 11},
+		{`(tag:go OR name:x`, 1},
+		{`tag:go )`, 8},
+		{`colour:red`, 1},
+		{`tag:`, 5},
+		{`updated:>soon`, 10},
+		{`body:"unit test`, 6},
+		{`OR tag:go`, 1},
+	}
+	for _, tt := range errorTests {
+		_, _, err := ParseQuery(tt.query, time.Now())
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) || queryErr.Pos != tt.pos {
+			t.Errorf("ParseQuery(%q) error = %v, want position %d", tt.query, err, tt.pos)
+			continue
+		}
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("ParseQuery(%q) error should match ErrInvalid", tt.query)
+		}
+	}
+	_, _, err := ParseQuery("tag:go )", time.Now())
+	if caret := err.(*QueryError).Caret(); caret != "tag:go )\n       ^" {
+		t.Errorf("Caret() = %q", caret)
+	}
+}
+
+func TestQueryDateBoundaries(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	for _, p := range []struct {
+		name string
+		at   time.Time
+	}{
+		{"before", day.Add(-time.Second)},
+		{"start", day},
+		{"end", day.AddDate(0, 0, 1).Add(-time.Second)},
+		{"after", day.AddDate(0, 0, 1)},
+	} {
+		if err := store.AddPrompt(p.name, "body", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.db.Exec("UPDATE prompts SET updated_at = ? WHERE name = ?", p.at.UTC().Format(sqliteTimeLayout), p.name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A plain date is a whole day: > starts after it and <= includes all of it
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`updated:>2026-01-01`, []string{"after"}},
+		{`updated:>=2026-01-01`, []string{"after", "end", "start"}},
+		{`updated:<2026-01-01`, []string{"before"}},
+		{`updated:<=2026-01-01`, []string{"before", "end", "start"}},
+		{`updated:2026-01-01`, []string{"end", "start"}},
+	}
+	for _, tt := range tests {
+		prompts, err := app.QueryPrompts(tt.query)
+		if err != nil {
+			t.Fatalf("QueryPrompts(%q) failed: %v", tt.query, err)
+		}
+		var got []string
+		for _, p := range prompts {
+			got = append(got, p.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("QueryPrompts(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestUsageFrecency(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+	for _, name := range []string{"daily", "old-favourite", "unused"} {
+		if _, err := app.CreatePrompt(name, "body", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	// Five uses long ago score less than two this week
+	for i := 0; i < 5; i++ {
+		if err := store.RecordUsageAt("old-favourite", UsageRun, now.AddDate(0, 0, -200-i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, days := range []int{1, 2} {
+		if err := store.RecordUsageAt("daily", UsageCopy, now.AddDate(0, 0, -days)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(used []PromptUsage) string {
+		var got []string
+		for _, u := range used {
+			got = append(got, u.Name)
+		}
+		return strings.Join(got, ",")
+	}
+	top, err := app.TopPrompts(0)
+	if err != nil || names(top) != "daily,old-favourite" {
+		t.Fatalf("TopPrompts() = %s, %v", names(top), err)
+	}
+	if top[0].Score != 200 || top[1].Score != 50 || top[1].Count != 5 {
+		t.Errorf("Unexpected usage: %+v, %+v", top[0].Usage, top[1].Usage)
+	}
+	if recent, err := app.RecentPrompts(1); err != nil || names(recent) != "daily" {
+		t.Errorf("RecentPrompts(1) = %s, %v", names(recent), err)
+	}
+	if list, err := app.PromptNames(); err != nil || strings.Join(list, ",") != "daily,old-favourite,unused" {
+		t.Errorf("PromptNames() = %v, %v", list, err)
+	}
+
+	if err := app.RecordUsage("missing", UsageGet); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := app.RecordUsage("daily", "print"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+	if err := app.DeletePrompt("daily"); err != nil {
+		t.Fatal(err)
+	}
+	if top, err := app.TopPrompts(0); err != nil || names(top) != "old-favourite" {
+		t.Errorf("Usage should be deleted with its prompt, got %s, %v", names(top), err)
+	}
+}
+
+func TestPinnedPrompts(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := app.CreatePrompt(name, "body", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := app.RecordUsage("b", UsageGet); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := app.GetPrompt("c")
+	if err := app.SetPinned("c", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if names, err := app.PromptNames(); err != nil || strings.Join(names, ",") != "c,b,a" {
+		t.Errorf("PromptNames() = %v, %v; want pinned, then used, then the rest", names, err)
+	}
+	pinned, err := app.FilterPrompts(PromptFilter{Pinned: true})
+	if err != nil || len(pinned) != 1 || pinned[0].Name != "c" || !pinned[0].Pinned {
+		t.Errorf("FilterPrompts(Pinned) = %+v, %v", pinned, err)
+	}
+	if !pinned[0].UpdatedAt.Equal(before.UpdatedAt) {
+		t.Error("Pinning should not change updated_at")
+	}
+	if err := app.SetPinned("c", false); err != nil {
+		t.Fatal(err)
+	}
+	if pinned, _ := app.FilterPrompts(PromptFilter{Pinned: true}); len(pinned) != 0 {
+		t.Errorf("Expected no pinned prompts after unpinning, got %v", pinned)
+	}
+	if err := app.SetPinned("missing", true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+	for _, name := range []string{"code", "code/review/go", "code/review/py", "code/tests", "writing/email"} {
+		if _, err := app.CreatePrompt(name, "body", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"/code", "code/", "code//go"} {
+		if err := ValidatePromptName(name); !errors.Is(err, ErrInvalid) {
+			t.Errorf("ValidatePromptName(%q) = %v, want ErrInvalid", name, err)
+		}
+	}
+
+	names := func(list []Prompt) string {
+		var got []string
+		for _, p := range list {
+			got = append(got, p.Name)
+		}
+		return strings.Join(got, ",")
+	}
+	if list, err := app.ListNamespace("code/review"); err != nil || names(list) != "code/review/go,code/review/py" {
+		t.Errorf("ListNamespace(code/review) = %s, %v", names(list), err)
+	}
+
+	all, _ := app.PromptNames()
+	completions := []struct {
+		toComplete string
+		want       string
+	}{
+		{"", "code,code/,writing/"},
+		{"co", "code,code/"},
+		{"code/", "code/review/,code/tests"},
+		{"code/review/", "code/review/go,code/review/py"},
+		{"x", ""},
+	}
+	for _, tt := range completions {
+		if got := strings.Join(CompleteNameSegment(all, tt.toComplete), ","); got != tt.want {
+			t.Errorf("CompleteNameSegment(%q) = %s, want %s", tt.toComplete, got, tt.want)
+		}
+	}
+
+	list, _ := app.ListPrompts("")
+	tree := NameTree(list)
+	if len(tree.Children) != 2 || tree.Children[0].Segment != "code" || tree.Children[0].Prompt == nil || len(tree.Children[0].Children) != 2 {
+		t.Errorf("Unexpected tree root: %+v", tree.Children)
+	}
+
+	moves := []struct {
+		from, to string
+		want     string // every name after the move
+		wantErr  error
+	}{
+		{"code/", "eng/code/", "code,eng/code/review/go,eng/code/review/py,eng/code/tests,writing/email", nil},
+		{"eng/", "eng/old/", "code,eng/old/code/review/go,eng/old/code/review/py,eng/old/code/tests,writing/email", nil},
+		{"writing/email", "eng/", "code,eng/email,eng/old/code/review/go,eng/old/code/review/py,eng/old/code/tests", nil},
+		{"eng/email", "code", "", ErrDuplicate},
+		{"eng/old/", "eng", "", ErrInvalid},
+		{"missing/", "eng/", "", ErrNotFound},
+		{"eng/old/code/", "eng/x//", "", ErrInvalid},
+	}
+	for _, tt := range moves {
+		_, err := app.MovePrompts(tt.from, tt.to)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("MovePrompts(%q, %q) error = %v, want %v", tt.from, tt.to, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr != nil {
+			continue
+		}
+		list, _ := app.ListPrompts("")
+		if got := names(list); got != tt.want {
+			t.Errorf("After MovePrompts(%q, %q) names = %s, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// setupGitEnv skips the test without git and gives commits a fixed identity.
+func setupGitEnv(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "p test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "p@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "p test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "p@example.com")
+}
+
+// setupGitSyncPair makes two libraries that sync through a shared bare
+// repository, checked out in root/a and root/b.
+func setupGitSyncPair(t *testing.T) (root string, storeA *SQLitePromptStore, appA *App, storeB *SQLitePromptStore, appB *App) {
+	setupGitEnv(t)
+	root = t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	if _, err := (gitRepo{dir: root}).run("init", "-q", "--bare", remote); err != nil {
+		t.Fatal(err)
+	}
+
+	storeA, pathA := setupTestDB(t)
+	appA = NewApp(storeA, pathA)
+	storeB, pathB := setupTestDB(t)
+	appB = NewApp(storeB, pathB)
+	for _, setup := range []struct {
+		app *App
+		dir string
+	}{{appA, "a"}, {appB, "b"}} {
+		if _, err := setup.app.InitGitSync(filepath.Join(root, setup.dir), remote, ImportOptions{OnConflict: ConflictSkip}); err != nil {
+			t.Fatalf("InitGitSync failed: %v", err)
+		}
+	}
+	return root, storeA, appA, storeB, appB
+}
+
+func TestGitSyncBetweenLibraries(t *testing.T) {
+	_, storeA, appA, storeB, appB := setupGitSyncPair(t)
+
+	// A adds a prompt and pushes it, B pulls it in
+	if err := storeA.AddPrompt("shared", "original", "team"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := appA.SyncGit()
+	if err != nil {
+		t.Fatalf("SyncGit A failed: %v", err)
+	}
+	if result.Commit != "Add prompt shared" || !result.Pushed {
+		t.Errorf("Unexpected sync result for A: %+v", result)
+	}
+	result, err = appB.SyncGit()
+	if err != nil {
+		t.Fatalf("SyncGit B failed: %v", err)
+	}
+	if len(result.Imported.Added) != 1 {
+		t.Errorf("Expected B to import 1 prompt, got %+v", result.Imported)
+	}
+
+	// Both sides edit the same prompt; B's sync must stop on a conflict
+	if err := storeA.UpdatePrompt("shared", "edited on A", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appA.SyncGit(); err != nil {
+		t.Fatalf("SyncGit A failed: %v", err)
+	}
+	if err := storeB.UpdatePrompt("shared", "edited on B", "team"); err != nil {
+		t.Fatal(err)
+	}
+	result, err = appB.SyncGit()
+	if err != nil {
+		t.Fatalf("SyncGit B failed: %v", err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "shared" {
+		t.Fatalf("Expected conflict on 'shared', got %+v", result)
+	}
+	if got, _ := storeB.GetPromptByName("shared"); got.Prompt != "edited on B" {
+		t.Errorf("Conflict must not overwrite local prompt, got %q", got.Prompt)
+	}
+
+	if err := appB.ResolveGitConflict("shared", true); err != nil {
+		t.Fatalf("ResolveGitConflict failed: %v", err)
+	}
+	if _, err := appB.SyncGit(); err != nil {
+		t.Fatalf("SyncGit B after resolve failed: %v", err)
+	}
+	if got, _ := storeB.GetPromptByName("shared"); got.Prompt != "edited on A" {
+		t.Errorf("Expected remote version after resolve, got %q", got.Prompt)
+	}
+
+	// A description-only edit is a change like any other
+	if err := storeB.SetDescription("shared", "Shared by the team"); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := appB.SyncGit(); err != nil || result.Commit != "Update prompt shared" {
+		t.Fatalf("Expected B to commit the description, got %+v, %v", result, err)
+	}
+	if result, err := appA.SyncGit(); err != nil || len(result.Imported.Updated) != 1 {
+		t.Fatalf("Expected A to import the description, got %+v, %v", result, err)
+	}
+	if got, _ := storeA.GetPromptByName("shared"); got.Description != "Shared by the team" {
+		t.Errorf("Expected the description on A, got %q", got.Description)
+	}
+}
+
+// TestNamespaceFilesRoundTrip checks that a/b and a_b, which used to share a
+// file name, survive export and import and both kinds of sync.
+func TestNamespaceFilesRoundTrip(t *testing.T) {
+	names := []string{"a/b", "a_b"}
+	newLibrary := func(t *testing.T) (*SQLitePromptStore, *App) {
+		store, dbPath := setupTestDB(t)
+		for _, name := range names {
+			if err := store.AddPrompt(name, "body of "+name, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return store, NewApp(store, dbPath)
+	}
+	assertFiles := func(t *testing.T, dir string) {
+		t.Helper()
+		for _, file := range []string{"a/b.md", "a_b.md"} {
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file))); err != nil {
+				t.Errorf("Expected %s to be written: %v", file, err)
+			}
+		}
+	}
+	assertPrompts := func(t *testing.T, store *SQLitePromptStore) {
+		t.Helper()
+		list, err := store.ListPrompts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != len(names) {
+			t.Fatalf("Expected %d prompts, got %+v", len(names), list)
+		}
+		for i, name := range names {
+			if list[i].Name != name || list[i].Prompt != "body of "+name {
+				t.Errorf("Expected prompt %q, got %+v", name, list[i])
+			}
+		}
+	}
+
+	t.Run("export and import", func(t *testing.T) {
+		store, _ := newLibrary(t)
+		list, err := store.ListPrompts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		if err := WriteMarkdownDir(dir, list, false); err != nil {
+			t.Fatalf("WriteMarkdownDir failed: %v", err)
+		}
+		assertFiles(t, dir)
+
+		// Without front matter, names come from the paths
+		if err := os.WriteFile(filepath.Join(dir, "a", "b.md"), []byte("body of a/b"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadMarkdownDir(dir)
+		if err != nil {
+			t.Fatalf("ReadMarkdownDir failed: %v", err)
+		}
+		target, targetPath := setupTestDB(t)
+		if _, err := NewApp(target, targetPath).ImportPrompts(read, ImportOptions{OnConflict: ConflictSkip}); err != nil {
+			t.Fatalf("ImportPrompts failed: %v", err)
+		}
+		assertPrompts(t, target)
+	})
+
+	t.Run("git sync", func(t *testing.T) {
+		setupGitEnv(t)
+		root := t.TempDir()
+		remote := filepath.Join(root, "remote.git")
+		if _, err := (gitRepo{dir: root}).run("init", "-q", "--bare", remote); err != nil {
+			t.Fatal(err)
+		}
+		_, appA := newLibrary(t)
+		storeB, pathB := setupTestDB(t)
+		appB := NewApp(storeB, pathB)
+		for _, setup := range []struct {
+			app *App
+			dir string
+		}{{appA, "a"}, {appB, "b"}} {
+			if _, err := setup.app.InitGitSync(filepath.Join(root, setup.dir), remote, ImportOptions{OnConflict: ConflictSkip}); err != nil {
+				t.Fatalf("InitGitSync failed: %v", err)
+			}
+		}
+		for _, app := range []*App{appA, appB, appA} {
+			if _, err := app.SyncGit(); err != nil {
+				t.Fatalf("SyncGit failed: %v", err)
+			}
+		}
+		assertFiles(t, filepath.Join(root, "b"))
+		assertPrompts(t, storeB)
+
+		// Deleting the namespaced prompt removes its file and the emptied directory
+		if err := storeB.DeletePrompt("a/b"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := appB.SyncGit(); err != nil {
+			t.Fatalf("SyncGit failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(root, "b", "a")); !os.IsNotExist(err) {
+			t.Errorf("Expected the empty namespace directory to be removed, got %v", err)
+		}
+	})
+
+	t.Run("dir sync", func(t *testing.T) {
+		_, app := newLibrary(t)
+		dir := t.TempDir()
+		if _, err := app.SyncDir(dir, PreferNone); err != nil {
+			t.Fatalf("SyncDir failed: %v", err)
+		}
+		assertFiles(t, dir)
+
+		store, dbPath := setupTestDB(t)
+		result, err := NewApp(store, dbPath).SyncDir(dir, PreferNone)
+		if err != nil {
+			t.Fatalf("SyncDir failed: %v", err)
+		}
+		if len(result.ToLibrary.Added) != len(names) {
+			t.Errorf("Expected both prompts to be added, got %+v", result.ToLibrary)
+		}
+		assertPrompts(t, store)
+	})
+}
+
+func TestGitSyncImportsUnappliedCommits(t *testing.T) {
+	root, storeA, appA, storeB, appB := setupGitSyncPair(t)
+	name := "team/shared?"
+	if err := storeA.AddPrompt(name, "original", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, app := range []*App{appA, appB} {
+		if _, err := app.SyncGit(); err != nil {
+			t.Fatalf("SyncGit failed: %v", err)
+		}
+	}
+
+	// B pulls A's edit but never imports it, as when a sync fails after pulling
+	if err := storeA.UpdatePrompt(name, "edited on A", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appA.SyncGit(); err != nil {
+		t.Fatalf("SyncGit A failed: %v", err)
+	}
+	if _, err := (gitRepo{dir: filepath.Join(root, "b")}).run("pull", "-q", "--no-rebase", "origin", "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := appB.SyncGit()
+	if err != nil {
+		t.Fatalf("SyncGit B failed: %v", err)
+	}
+	if result.Commit != "" {
+		t.Errorf("Expected the stale library not to be committed, got %q", result.Commit)
+	}
+	if got, _ := storeB.GetPromptByName(name); got.Prompt != "edited on A" {
+		t.Errorf("Expected the pulled edit to be imported, got %q", got.Prompt)
+	}
+
+	// Conflicts are reported by prompt name, not by escaped file path
+	if err := storeA.UpdatePrompt(name, "second edit on A", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeB.UpdatePrompt(name, "edit on B", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appA.SyncGit(); err != nil {
+		t.Fatalf("SyncGit A failed: %v", err)
+	}
+	result, err = appB.SyncGit()
+	if err != nil {
+		t.Fatalf("SyncGit B failed: %v", err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != name {
+		t.Fatalf("Expected a conflict on %q, got %+v", name, result.Conflicts)
+	}
+	if err := appB.ResolveGitConflict(name, false); err != nil {
+		t.Errorf("ResolveGitConflict failed: %v", err)
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+	dir := t.TempDir()
+
+	if err := store.AddPrompt("greeting", "Say hello", "basic"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := app.SyncDir(dir, PreferNone)
+	if err != nil {
+		t.Fatalf("SyncDir failed: %v", err)
+	}
+	if len(result.ToFolder.Added) != 1 {
+		t.Fatalf("Expected greeting to be written to the folder, got %+v", result)
+	}
+
+	// A folder edit flows into the library, a new file becomes a prompt
+	file := filepath.Join(dir, "greeting.md")
+	if err := os.WriteFile(file, []byte("---\nname: greeting\ntags: [basic]\n---\nSay hello warmly"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "farewell.md"), []byte("Say goodbye"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.SyncDir(dir, PreferNone); err != nil {
+		t.Fatalf("SyncDir failed: %v", err)
+	}
+	if got, _ := store.GetPromptByName("greeting"); got.Prompt != "Say hello warmly" {
+		t.Errorf("Expected folder edit in library, got %q", got.Prompt)
+	}
+	if _, err := store.GetPromptByName("farewell"); err != nil {
+		t.Errorf("Expected new file to be added: %v", err)
+	}
+
+	// Changing both sides is a conflict that leaves both untouched until a preference is given
+	if err := store.UpdatePrompt("greeting", "Library edit", "basic"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("---\nname: greeting\n---\nFolder edit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = app.SyncDir(dir, PreferNone)
+	if err != nil {
+		t.Fatalf("SyncDir failed: %v", err)
+	}
+	if len(result.Conflicts) != 1 || !strings.Contains(result.Conflicts[0].Base, "Say hello warmly") {
+		t.Fatalf("Expected one conflict with the base version, got %+v", result.Conflicts)
+	}
+
+	// The library keeps only a hash of the base; its text lives in the folder
+	var hash string
+	if err := store.db.QueryRow("SELECT hash FROM dir_sync_state WHERE name = 'greeting'").Scan(&hash); err != nil {
+		t.Fatal(err)
+	}
+	if hash != contentHash(result.Conflicts[0].Base) {
+		t.Errorf("Expected the sync state to hold the base hash, got %q", hash)
+	}
+	if err := os.Remove(filepath.Join(dir, dirSyncBaseDir, "greeting.md")); err != nil {
+		t.Fatalf("Expected a base copy in the folder: %v", err)
+	}
+	if result, err := app.SyncDir(dir, PreferNone); err != nil || len(result.Conflicts) != 1 || result.Conflicts[0].Base != "" {
+		t.Errorf("Expected the conflict without a base once its copy is gone, got %+v, %v", result, err)
+	}
+	if got, _ := store.GetPromptByName("greeting"); got.Prompt != "Library edit" {
+		t.Errorf("Conflict must not change the library, got %q", got.Prompt)
+	}
+
+	if _, err := app.SyncDir(dir, PreferFolder); err != nil {
+		t.Fatalf("SyncDir failed: %v", err)
+	}
+	if got, _ := store.GetPromptByName("greeting"); got.Prompt != "Folder edit" {
+		t.Errorf("Expected folder version to win, got %q", got.Prompt)
+	}
+
+	// Description-only edits sync both ways
+	if err := os.WriteFile(file, []byte("---\nname: greeting\ndescription: Greets\n---\nFolder edit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := app.SyncDir(dir, PreferNone); err != nil || len(result.ToLibrary.Updated) != 1 {
+		t.Fatalf("Expected the folder description to update the library, got %+v, %v", result, err)
+	}
+	if got, _ := store.GetPromptByName("greeting"); got.Description != "Greets" {
+		t.Errorf("Expected the description in the library, got %q", got.Description)
+	}
+	if err := store.SetDescription("greeting", "Greets warmly"); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := app.SyncDir(dir, PreferNone); err != nil || len(result.ToFolder.Updated) != 1 {
+		t.Fatalf("Expected the library description to update the folder, got %+v, %v", result, err)
+	}
+	if data, _ := os.ReadFile(file); !strings.Contains(string(data), "description: Greets warmly\n") {
+		t.Errorf("Expected the description in the file, got %q", data)
+	}
+
+	// Deleting a file deletes the prompt
+	if err := os.Remove(filepath.Join(dir, "farewell.md")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.SyncDir(dir, PreferNone); err != nil {
+		t.Fatalf("SyncDir failed: %v", err)
+	}
+	if _, err := store.GetPromptByName("farewell"); err == nil {
+		t.Error("Expected farewell to be deleted from the library")
+	}
+}
+
+func TestRenderPrompt(t *testing.T) {
+	content := "Review {{lang}} code for {{ focus }}. Use {{lang}} idioms."
+	if got := TemplateVariables(content); strings.Join(got, ",") != "lang,focus" {
+		t.Errorf("TemplateVariables() = %v", got)
+	}
+	rendered, err := Render(content, map[string]string{"lang": "Go", "focus": "races"})
+	if err != nil || rendered != "Review Go code for races. Use Go idioms." {
+		t.Errorf("Render() = %q, %v", rendered, err)
+	}
+	if _, err := Render(content, map[string]string{"lang": "Go"}); err == nil || !strings.Contains(err.Error(), "focus") {
+		t.Errorf("Expected missing variable error naming focus, got %v", err)
+	}
+	highlighted := HighlightVariables(content, func(v string) string { return "[" + v + "]" })
+	if highlighted != "Review [{{lang}}] code for [{{ focus }}]. Use [{{lang}}] idioms." {
+		t.Errorf("HighlightVariables() = %q", highlighted)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	since, err := ParseSince("7d", now)
+	if err != nil || !since.Equal(now.AddDate(0, 0, -7)) {
+		t.Errorf("ParseSince(7d) = %v, %v", since, err)
+	}
+	if _, err := ParseSince("2026-01-01", now); err != nil {
+		t.Errorf("ParseSince(date) failed: %v", err)
+	}
+	if _, err := ParseSince("yesterday", now); err == nil {
+		t.Error("Expected error for invalid since value")
+	}
+}
+
+func TestContentProviders(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+
+	if _, err := app.AddPromptWith("piped", "cli", ReaderContent{R: strings.NewReader("  from stdin\n")}); err != nil {
+		t.Fatalf("AddPromptWith(reader) failed: %v", err)
+	}
+	if p, _ := app.GetPrompt("piped"); p.Prompt != "  from stdin\n" {
+		t.Errorf("Expected stdin content stored byte-exact, got %q", p.Prompt)
+	}
+
+	path := filepath.Join(t.TempDir(), "body.txt")
+	if err := os.WriteFile(path, []byte("from file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.AddPromptWith("filed", "", FileContent{Path: path}); err != nil {
+		t.Fatalf("AddPromptWith(file) failed: %v", err)
+	}
+
+	empty := ContentFunc(func(string) (string, error) { return "", nil })
+	if _, err := app.AddPromptWith("cancelled", "", empty); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Expected ErrCancelled, got %v", err)
+	}
+
+	same := ContentFunc(func(initial string) (string, error) { return initial, nil })
+	result, err := app.EditPromptWith("filed", nil, same)
+	if err != nil || result.Changed {
+		t.Errorf("Expected unchanged edit, got %+v, %v", result, err)
+	}
+	tags := "new"
+	result, err = app.EditPromptWith("filed", &tags, same)
+	if err != nil || !result.Changed || result.Prompt.Tags != "new" {
+		t.Errorf("Expected tag edit, got %+v, %v", result, err)
+	}
+
+	if err := app.SetNormalization(NormalizeTrailingNewline); err != nil {
+		t.Fatal(err)
+	}
+	full := strings.Repeat("a", MaxPromptContentLen)
+	if _, err := app.AddPromptWith("full", "", ReaderContent{R: strings.NewReader(full + "\n")}); err != nil {
+		t.Errorf("Expected content at the limit once its final newline is dropped, got %v", err)
+	}
+	if _, err := app.AddPromptWith("over", "", ReaderContent{R: strings.NewReader(full + "a\n")}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for content over the limit, got %v", err)
+	}
+}
+
+func TestDescriptionAndRename(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+	for _, name := range []string{"a", "b"} {
+		if _, err := app.CreatePrompt(name, "content", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := app.SetDescription("a", "  Says hello  "); err != nil {
+		t.Fatalf("SetDescription failed: %v", err)
+	}
+	if err := app.SetDescription("a", strings.Repeat("x", MaxPromptDescriptionLen+1)); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for long description, got %v", err)
+	}
+	if err := app.RenamePrompt("a", "b"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate renaming onto an existing name, got %v", err)
+	}
+	if err := app.RenamePrompt("missing", "c"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound renaming a missing prompt, got %v", err)
+	}
+	if err := app.RenamePrompt("a", "c"); err != nil {
+		t.Fatalf("RenamePrompt failed: %v", err)
+	}
+	p, err := app.GetPrompt("c")
+	if err != nil || p.Description != "Says hello" {
+		t.Errorf("Expected renamed prompt with description, got %+v, %v", p, err)
+	}
+}
+
+func TestOpenDBBusyTimeout(t *testing.T) {
+	db, err := OpenDB(filepath.Join(t.TempDir(), "prompts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var timeout int
+	if err := db.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil || timeout != busyTimeoutMillis {
+		t.Errorf("busy_timeout = %d, %v; want %d", timeout, err, busyTimeoutMillis)
+	}
+}
+
+func TestSavePrompt(t *testing.T) {
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+
+	created, err := app.SavePrompt("", PromptFields{Name: "a", Tags: "go, go", Description: " Says hi ", Body: "hello"})
+	if err != nil {
+		t.Fatalf("SavePrompt create failed: %v", err)
+	}
+	if created.Tags != "go" || created.Description != "Says hi" || created.Prompt != "hello" {
+		t.Errorf("Unexpected created prompt: %+v", created)
+	}
+	if _, err := app.CreatePrompt("b", "taken", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// A save that fails part way leaves every field as it was
+	failing := []struct {
+		name   string
+		fields PromptFields
+		want   error
+	}{
+		{"name taken", PromptFields{Name: "b", Description: "new", Body: "new body"}, ErrDuplicate},
+		{"long description", PromptFields{Name: "c", Description: strings.Repeat("x", MaxPromptDescriptionLen+1), Body: "new body"}, ErrInvalid},
+		{"empty body", PromptFields{Name: "c", Description: "new", Body: " "}, ErrInvalid},
+	}
+	for _, tt := range failing {
+		if _, err := app.SavePrompt("a", tt.fields); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+		if got, err := app.GetPrompt("a"); err != nil || got.Prompt != "hello" || got.Description != "Says hi" {
+			t.Errorf("%s: expected prompt a to be unchanged, got %+v, %v", tt.name, got, err)
+		}
+	}
+
+	saved, err := app.SavePrompt("a", PromptFields{Name: "code/a", Tags: "go", Body: "hello again"})
+	if err != nil {
+		t.Fatalf("SavePrompt edit failed: %v", err)
+	}
+	if saved.Name != "code/a" || saved.Description != "" || saved.Prompt != "hello again" {
+		t.Errorf("Unexpected saved prompt: %+v", saved)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	render := func(diff []DiffLine) string {
+		var b strings.Builder
+		for _, line := range diff {
+			b.WriteString([]string{" ", "-", "+"}[line.Op] + line.Text + "\n")
+		}
+		return b.String()
+	}
+	tests := []struct {
+		name, a, b, want string
+	}{
+		{"same", "a\nb", "a\nb", " a\n b\n"},
+		{"insert", "a\nc", "a\nb\nc", " a\n+b\n c\n"},
+		{"delete", "a\nb\nc", "a\nc", " a\n-b\n c\n"},
+		{"replace", "a\nb", "a\nx", " a\n-b\n+x\n"},
+		{"from empty", "", "a", "+a\n"},
+		{"to empty", "a", "", "-a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(DiffLines(tt.a, tt.b)); got != tt.want {
+				t.Errorf("DiffLines() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalization(t *testing.T) {
+	tests := []struct {
+		policy Normalization
+		in     string
+		want   string
+	}{
+		{NormalizeNone, "  code\n\n", "  code\n\n"},
+		{NormalizeTrailingNewline, "  code\n\n", "  code\n"},
+		{NormalizeTrailingNewline, "code\r\n", "code"},
+		{NormalizeTrailingNewline, "code", "code"},
+		{NormalizeFull, "  code\n\n", "code"},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Apply(tt.in); got != tt.want {
+			t.Errorf("%s.Apply(%q) = %q, want %q", tt.policy, tt.in, got, tt.want)
+		}
+	}
+
+	store, dbPath := setupTestDB(t)
+	app := NewApp(store, dbPath)
+	if n, err := app.Normalization(); err != nil || n != DefaultNormalization {
+		t.Errorf("Normalization() = %q, %v; want the default", n, err)
+	}
+	if err := app.SetNormalization("trim"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for unknown policy, got %v", err)
+	}
+	if err := app.SetNormalization(NormalizeNone); err != nil {
+		t.Fatal(err)
+	}
+	body := "    indented example\n\n"
+	p, err := app.CreatePrompt("exact", body, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Prompt != body {
+		t.Errorf("Stored %q, want byte-exact %q", p.Prompt, body)
+	}
+	if changed, err := app.EditPrompt(p, body, ""); err != nil || changed {
+		t.Errorf("EditPrompt() with the same body = %v, %v; want unchanged", changed, err)
+	}
+}
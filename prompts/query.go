@@ -0,0 +1,348 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A query selects prompts with field terms combined by AND, OR, NOT and parentheses:
+//
+//	tag:go AND (name:review* OR body:"unit test") NOT tag:draft updated:>2026-01-01
+//
+// Terms next to each other are ANDed. A bare word matches the name or body.
+// name:, body: and desc: match a substring, or the whole value when it contains
+// a * wildcard; tag: matches one tag, with * wildcards. updated: and created:
+// take a comparison (>, >=, <, <=, or none for the same day) and a date,
+// timestamp or age such as 7d; a date compares whole days, so >2026-01-01
+// starts on January 2. All text matching is case-insensitive.
+
+// QueryError is a query syntax error at a 1-based character position.
+type QueryError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos, e.Msg)
+}
+
+// Is makes query errors match ErrInvalid.
+func (e *QueryError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// Caret returns the query with a marker under the position of the error.
+func (e *QueryError) Caret() string {
+	return e.Query + "\n" + strings.Repeat(" ", max(e.Pos-1, 0)) + "^"
+}
+
+// queryFields maps the field names accepted before a colon to prompt columns.
+var queryFields = map[string]string{
+	"name":        "name",
+	"body":        "prompt",
+	"desc":        "COALESCE(description, '')",
+	"description": "COALESCE(description, '')",
+	"tag":         "tag",
+	"updated":     "updated_at",
+	"created":     "created_at",
+}
+
+// queryNode is a parsed query expression that renders itself as an SQL condition.
+type queryNode interface {
+	sql() (string, []any)
+}
+
+type queryAnd struct{ left, right queryNode }
+type queryOr struct{ left, right queryNode }
+type queryNot struct{ expr queryNode }
+
+// queryMatch is a text term against a column, or against name and body when column is empty.
+type queryMatch struct {
+	column string
+	value  string
+}
+
+// queryTime compares a timestamp column with a time; op "" means the same day.
+// For a plain date, day is set and the comparison covers whole days, so
+// >2026-01-01 starts at the next midnight and <=2026-01-01 ends there.
+type queryTime struct {
+	column string
+	op     string
+	at     time.Time
+	day    bool
+}
+
+func (n queryAnd) sql() (string, []any) {
+	l, largs := n.left.sql()
+	r, rargs := n.right.sql()
+	return "(" + l + " AND " + r + ")", append(largs, rargs...)
+}
+
+func (n queryOr) sql() (string, []any) {
+	l, largs := n.left.sql()
+	r, rargs := n.right.sql()
+	return "(" + l + " OR " + r + ")", append(largs, rargs...)
+}
+
+func (n queryNot) sql() (string, []any) {
+	s, args := n.expr.sql()
+	return "NOT " + s, args
+}
+
+func (n queryMatch) sql() (string, []any) {
+	switch n.column {
+	case "":
+		pattern := likePattern(n.value, false)
+		return `(name LIKE ? ESCAPE '\' OR prompt LIKE ? ESCAPE '\')`, []any{pattern, pattern}
+	case "tag":
+		// Tags are stored comma-separated, so wrapping them in commas anchors one tag
+		pattern := "%," + likePattern(n.value, true) + ",%"
+		return `(',' || COALESCE(tags, '') || ',') LIKE ? ESCAPE '\'`, []any{pattern}
+	}
+	return n.column + ` LIKE ? ESCAPE '\'`, []any{likePattern(n.value, false)}
+}
+
+func (n queryTime) sql() (string, []any) {
+	start := n.at.UTC().Format(sqliteTimeLayout)
+	next := n.at.AddDate(0, 0, 1).UTC().Format(sqliteTimeLayout)
+	switch {
+	case n.op == "":
+		return "(" + n.column + " >= ? AND " + n.column + " < ?)", []any{start, next}
+	case n.day && n.op == ">":
+		return n.column + " >= ?", []any{next}
+	case n.day && n.op == "<=":
+		return n.column + " < ?", []any{next}
+	}
+	return n.column + " " + n.op + " ?", []any{start}
+}
+
+// likeEscaper escapes the LIKE wildcards and the escape character itself.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// likePattern escapes value for LIKE and turns * into %. Without a wildcard,
+// exact matches the whole value and otherwise a substring is matched.
+func likePattern(value string, exact bool) string {
+	escaped := likeEscaper.Replace(value)
+	if strings.Contains(value, "*") {
+		return strings.ReplaceAll(escaped, "*", "%")
+	}
+	if exact {
+		return escaped
+	}
+	return "%" + escaped + "%"
+}
+
+// queryToken is a lexical token: a word (possibly field:value), a quoted string, or punctuation.
+type queryToken struct {
+	pos    int // 1-based position in the query
+	text   string
+	quoted bool
+}
+
+// lexQuery splits a query into tokens. A quoted string directly after a
+// colon, as in body:"unit test", becomes part of its word.
+func lexQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, queryToken{pos: i + 1, text: string(r)})
+			i++
+		default:
+			start := i
+			var text strings.Builder
+			quoted := false
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				if runes[i] != '"' {
+					text.WriteRune(runes[i])
+					i++
+					continue
+				}
+				end := i + 1
+				for end < len(runes) && runes[end] != '"' {
+					end++
+				}
+				if end == len(runes) {
+					return nil, &QueryError{Query: query, Pos: i + 1, Msg: "unterminated quote"}
+				}
+				text.WriteString(string(runes[i+1 : end]))
+				quoted = true
+				i = end + 1
+			}
+			tokens = append(tokens, queryToken{pos: start + 1, text: text.String(), quoted: quoted})
+		}
+	}
+	return tokens, nil
+}
+
+// queryParser is a recursive-descent parser over the tokens of a query.
+type queryParser struct {
+	query  string
+	tokens []queryToken
+	next   int
+	now    time.Time
+}
+
+func (p *queryParser) peek() *queryToken {
+	if p.next < len(p.tokens) {
+		return &p.tokens[p.next]
+	}
+	return nil
+}
+
+// keyword reports whether the next token is the unquoted keyword kw.
+func (p *queryParser) keyword(kw string) bool {
+	t := p.peek()
+	return t != nil && !t.quoted && t.text == kw
+}
+
+func (p *queryParser) errorf(pos int, format string, args ...any) error {
+	return &QueryError{Query: p.query, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// endPos is the position just past the end of the query, for errors about missing input.
+func (p *queryParser) endPos() int {
+	return len([]rune(p.query)) + 1
+}
+
+// parseOr parses: and { OR and }
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		p.next++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = queryOr{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses: unary { [AND] unary }
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.keyword("AND") {
+			p.next++
+		} else if t := p.peek(); t == nil || t.text == ")" || p.keyword("OR") {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = queryAnd{left, right}
+	}
+}
+
+// parseUnary parses: NOT unary | ( or ) | term
+func (p *queryParser) parseUnary() (queryNode, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, p.errorf(p.endPos(), "expected a search term")
+	}
+	switch {
+	case p.keyword("NOT"):
+		p.next++
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return queryNot{expr}, nil
+	case !t.quoted && t.text == "(":
+		p.next++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing == nil || closing.text != ")" {
+			return nil, p.errorf(t.pos, "unclosed parenthesis")
+		}
+		p.next++
+		return expr, nil
+	case !t.quoted && (t.text == ")" || t.text == "AND" || t.text == "OR"):
+		return nil, p.errorf(t.pos, "unexpected %s", t.text)
+	}
+	p.next++
+	return p.parseTerm(*t)
+}
+
+// parseTerm turns a word into a field match, or a match on name and body.
+func (p *queryParser) parseTerm(t queryToken) (queryNode, error) {
+	field, value, ok := strings.Cut(t.text, ":")
+	column, known := queryFields[strings.ToLower(field)]
+	if !ok || !known {
+		if ok && !t.quoted {
+			return nil, p.errorf(t.pos, "unknown field '%s' (use tag, name, body, desc, updated, or created)", field)
+		}
+		return queryMatch{value: t.text}, nil
+	}
+	valuePos := t.pos + len([]rune(field)) + 1
+	if value == "" {
+		return nil, p.errorf(valuePos, "missing value for %s:", field)
+	}
+	if column != "updated_at" && column != "created_at" {
+		return queryMatch{column: column, value: value}, nil
+	}
+
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<"} {
+		if rest, found := strings.CutPrefix(value, candidate); found {
+			op, value = candidate, rest
+			break
+		}
+	}
+	at, err := ParseSince(value, p.now)
+	if err != nil {
+		return nil, p.errorf(valuePos+len(op), "invalid date '%s'", value)
+	}
+	_, dateErr := time.Parse("2006-01-02", value)
+	day := dateErr == nil
+	if op == "" && !day {
+		// Only a plain date names a whole day; an age or timestamp means "since"
+		op = ">="
+	}
+	return queryTime{column: column, op: op, at: at, day: day}, nil
+}
+
+// ParseQuery parses a query into an SQL condition on the prompts table and its arguments.
+// Syntax errors are *QueryError.
+func ParseQuery(query string, now time.Time) (string, []any, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return "", nil, err
+	}
+	p := &queryParser{query: query, tokens: tokens, now: now}
+	node, err := p.parseOr()
+	if err != nil {
+		return "", nil, err
+	}
+	if t := p.peek(); t != nil {
+		return "", nil, p.errorf(t.pos, "unexpected %s", t.text)
+	}
+	where, args := node.sql()
+	return where, args, nil
+}
+
+// QueryPrompts returns the prompts matching a query, ordered by name.
+func (a *App) QueryPrompts(query string) ([]Prompt, error) {
+	where, args, err := ParseQuery(query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return a.promptStore.ListPromptsWhere(where, args...)
+}
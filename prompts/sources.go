@@ -0,0 +1,234 @@
+package prompts
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// External prompt collections supported by import --from.
+const (
+	SourceAwesomeChatGPT = "awesome-chatgpt"
+	SourceFabric         = "fabric"
+	SourceChatML         = "chatml"
+)
+
+// Sources lists the valid --from values in the order shown to users.
+var Sources = []string{SourceAwesomeChatGPT, SourceFabric, SourceChatML}
+
+// sourceTags maps each source to the tag added to every prompt imported from it.
+var sourceTags = map[string]string{
+	SourceAwesomeChatGPT: "awesome-chatgpt-prompts",
+	SourceFabric:         "fabric",
+	SourceChatML:         "chatml",
+}
+
+// ReadSource reads prompts from an external collection at path and tags them with their source.
+func ReadSource(source, path string) ([]Prompt, error) {
+	var prompts []Prompt
+	var err error
+	switch source {
+	case SourceAwesomeChatGPT:
+		prompts, err = readAwesomeChatGPT(path)
+	case SourceFabric:
+		prompts, err = readFabricPatterns(path)
+	case SourceChatML:
+		prompts, err = readChatML(path)
+	default:
+		return nil, invalidf("invalid source '%s', must be one of: %s", source, strings.Join(Sources, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range prompts {
+		prompts[i].Tags = NormalizeTags(prompts[i].Tags + "," + sourceTags[source])
+	}
+	return prompts, nil
+}
+
+// slugify lowercases s and joins its words with hyphens, e.g. "Linux Terminal" becomes "linux-terminal".
+func slugify(s string) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			pendingHyphen = false
+		} else {
+			pendingHyphen = true
+		}
+	}
+	return b.String()
+}
+
+// readAwesomeChatGPT reads the awesome-chatgpt-prompts CSV, which has "act" and "prompt" columns.
+func readAwesomeChatGPT(path string) ([]Prompt, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	actCol, promptCol := -1, -1
+	for i, h := range rows[0] {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "act":
+			actCol = i
+		case "prompt":
+			promptCol = i
+		}
+	}
+	if actCol < 0 || promptCol < 0 {
+		return nil, fmt.Errorf("CSV header must contain 'act' and 'prompt' columns")
+	}
+
+	prompts := make([]Prompt, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		if actCol >= len(row) || promptCol >= len(row) {
+			continue
+		}
+		prompts = append(prompts, Prompt{Name: slugify(row[actCol]), Prompt: row[promptCol]})
+	}
+	return prompts, nil
+}
+
+// readFabricPatterns reads a Fabric patterns directory, where each pattern lives in <name>/system.md.
+// path may be the patterns directory itself or a directory containing it.
+func readFabricPatterns(path string) ([]Prompt, error) {
+	if info, err := os.Stat(filepath.Join(path, "patterns")); err == nil && info.IsDir() {
+		path = filepath.Join(path, "patterns")
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var prompts []Prompt
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, entry.Name(), "system.md"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading pattern '%s': %w", entry.Name(), err)
+		}
+		prompts = append(prompts, Prompt{Name: entry.Name(), Prompt: string(data)})
+	}
+	return prompts, nil
+}
+
+// chatMessage is a single OpenAI/ChatML message. Content is either a string or a list of parts.
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// chatConversation is a named list of messages, the shape used by chat completion requests.
+type chatConversation struct {
+	Name     string        `json:"name"`
+	Messages []chatMessage `json:"messages"`
+}
+
+// text returns the message content, joining the text parts of multi-part content.
+func (m chatMessage) text() (string, error) {
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return "", fmt.Errorf("unsupported content for %s message", m.Role)
+	}
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// readChatML reads OpenAI/ChatML message JSON: a single {"messages": [...]} object,
+// a list of such objects, or a bare list of messages.
+func readChatML(path string) ([]Prompt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	var conversations []chatConversation
+	var single chatConversation
+	var messages []chatMessage
+	switch {
+	case json.Unmarshal(data, &single) == nil && len(single.Messages) > 0:
+		conversations = []chatConversation{single}
+	case json.Unmarshal(data, &conversations) == nil && len(conversations) > 0 && len(conversations[0].Messages) > 0:
+	case json.Unmarshal(data, &messages) == nil && len(messages) > 0 && messages[0].Role != "":
+		conversations = []chatConversation{{Messages: messages}}
+	default:
+		return nil, fmt.Errorf("%s does not contain OpenAI/ChatML messages", path)
+	}
+
+	base := slugify(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	prompts := make([]Prompt, 0, len(conversations))
+	for i, conv := range conversations {
+		content, err := renderChatML(conv.Messages)
+		if err != nil {
+			return nil, err
+		}
+		name := conv.Name
+		if name == "" {
+			name = base
+			if len(conversations) > 1 {
+				name = fmt.Sprintf("%s-%d", base, i+1)
+			}
+		}
+		prompts = append(prompts, Prompt{Name: name, Prompt: content})
+	}
+	return prompts, nil
+}
+
+// renderChatML turns messages into prompt content. A lone message is stored as its text;
+// longer conversations keep their roles using ChatML markers.
+func renderChatML(messages []chatMessage) (string, error) {
+	if len(messages) == 1 {
+		return messages[0].text()
+	}
+	var b strings.Builder
+	for i, m := range messages {
+		text, err := m.text()
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "<|im_start|>%s\n%s<|im_end|>", m.Role, text)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,468 @@
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// settingSyncGitDir stores the path of the git repository used by p sync.
+const settingSyncGitDir = "sync.git.dir"
+
+// settingSyncGitApplied stores the last commit of the sync repository imported
+// into the library, so a sync that pulled but failed to import is finished
+// before the library is mirrored over the pulled files.
+const settingSyncGitApplied = "sync.git.applied"
+
+// SyncChanges lists the prompts a sync added, updated, or deleted on one side.
+type SyncChanges struct {
+	Added   []string
+	Updated []string
+	Deleted []string
+}
+
+// Empty reports whether no prompts changed.
+func (c SyncChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Updated) == 0 && len(c.Deleted) == 0
+}
+
+// GitSyncResult describes the outcome of a git sync.
+type GitSyncResult struct {
+	Commit    string      // subject of the commit recording local changes, if any
+	Pulled    bool        // whether a remote was fetched and merged
+	Pushed    bool        // whether the result was pushed
+	Imported  SyncChanges // changes applied to the library from the repository
+	Conflicts []string    // prompt files left conflicted by the merge
+}
+
+// gitRepo runs the system git binary inside a working tree.
+type gitRepo struct {
+	dir string
+}
+
+// run executes git with args and returns its trimmed stdout.
+func (g gitRepo) run(args ...string) (string, error) {
+	// Unquoted paths keep non-ASCII prompt file names readable
+	cmd := exec.Command("git", append([]string{"-c", "core.quotePath=false"}, args...)...)
+	cmd.Dir = g.dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		return "", fmt.Errorf("git %s failed: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// succeeds reports whether git exits successfully with args.
+func (g gitRepo) succeeds(args ...string) bool {
+	_, err := g.run(args...)
+	return err == nil
+}
+
+// branch returns the current branch name, which also works before the first commit.
+func (g gitRepo) branch() (string, error) {
+	return g.run("symbolic-ref", "--short", "HEAD")
+}
+
+// hasRemote reports whether an origin remote is configured.
+func (g gitRepo) hasRemote() bool {
+	return g.succeeds("remote", "get-url", "origin")
+}
+
+// remoteHasBranch reports whether origin already has branch.
+func (g gitRepo) remoteHasBranch(branch string) (bool, error) {
+	out, err := g.run("ls-remote", "--heads", "origin", branch)
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// head returns the current commit, or an empty string before the first commit.
+func (g gitRepo) head() string {
+	out, err := g.run("rev-parse", "-q", "--verify", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+// mergeInProgress reports whether a previous sync stopped on a conflicted merge.
+func (g gitRepo) mergeInProgress() bool {
+	return g.succeeds("rev-parse", "-q", "--verify", "MERGE_HEAD")
+}
+
+// unmergedFiles lists files with unresolved merge conflicts.
+func (g gitRepo) unmergedFiles() ([]string, error) {
+	out, err := g.run("diff", "--name-only", "--diff-filter=U")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// commitAll stages every change and commits it with a message describing the prompts touched.
+// It returns the commit subject, or an empty string if there was nothing to commit.
+func (g gitRepo) commitAll() (string, error) {
+	if _, err := g.run("add", "-A"); err != nil {
+		return "", err
+	}
+	status, err := g.run("diff", "--cached", "--name-status")
+	if err != nil || status == "" {
+		return "", err
+	}
+
+	var changes SyncChanges
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		name := promptNameFromFile(fields[len(fields)-1])
+		switch fields[0][0] {
+		case 'A':
+			changes.Added = append(changes.Added, name)
+		case 'D':
+			changes.Deleted = append(changes.Deleted, name)
+		default:
+			changes.Updated = append(changes.Updated, name)
+		}
+	}
+
+	subject, body := commitMessage(changes)
+	if _, err := g.run("commit", "-q", "-m", subject, "-m", body); err != nil {
+		return "", err
+	}
+	return subject, nil
+}
+
+// commitMessage describes prompt changes as a commit subject and body.
+func commitMessage(c SyncChanges) (string, string) {
+	var subject string
+	total := len(c.Added) + len(c.Updated) + len(c.Deleted)
+	switch {
+	case total == 1 && len(c.Added) == 1:
+		subject = fmt.Sprintf("Add prompt %s", c.Added[0])
+	case total == 1 && len(c.Updated) == 1:
+		subject = fmt.Sprintf("Update prompt %s", c.Updated[0])
+	case total == 1:
+		subject = fmt.Sprintf("Delete prompt %s", c.Deleted[0])
+	default:
+		subject = fmt.Sprintf("Sync %d prompt changes", total)
+	}
+
+	var body strings.Builder
+	for _, section := range []struct {
+		label string
+		names []string
+	}{{"Added", c.Added}, {"Updated", c.Updated}, {"Deleted", c.Deleted}} {
+		if len(section.names) > 0 {
+			fmt.Fprintf(&body, "%s: %s\n", section.label, strings.Join(section.names, ", "))
+		}
+	}
+	return subject, strings.TrimSpace(body.String())
+}
+
+// mirrorMarkdownDir makes dir hold exactly one Markdown file per prompt,
+// rewriting changed files and removing files for prompts that no longer exist.
+func mirrorMarkdownDir(dir string, prompts []Prompt) error {
+	if err := checkFileNames(prompts); err != nil {
+		return err
+	}
+	keep := make(map[string]struct{}, len(prompts))
+	for _, p := range prompts {
+		file := promptFileName(p.Name)
+		keep[file] = struct{}{}
+
+		data, err := marshalMarkdown(p, false)
+		if err != nil {
+			return fmt.Errorf("error exporting prompt '%s': %w", p.Name, err)
+		}
+		if existing, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file))); err == nil && bytes.Equal(existing, data) {
+			continue
+		}
+		if err := writeMarkdownFile(dir, file, data); err != nil {
+			return err
+		}
+	}
+
+	files, err := markdownFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if _, ok := keep[file]; !ok {
+			if err := removeMarkdownFile(dir, file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyPrompts makes the store match prompts exactly, in one transaction.
+func (a *App) applyPrompts(prompts []Prompt) (SyncChanges, error) {
+	var changes SyncChanges
+	tx, err := a.promptStore.Begin()
+	if err != nil {
+		return changes, err
+	}
+	defer tx.Rollback()
+
+	current, err := tx.ListPrompts()
+	if err != nil {
+		return changes, err
+	}
+	currentByName := make(map[string]Prompt, len(current))
+	for _, p := range current {
+		currentByName[p.Name] = p
+	}
+
+	seen := make(map[string]struct{}, len(prompts))
+	for _, p := range prompts {
+		if err := validateImportedPrompt(&p); err != nil {
+			return changes, fmt.Errorf("invalid prompt '%s': %w", p.Name, err)
+		}
+		seen[p.Name] = struct{}{}
+		existing, ok := currentByName[p.Name]
+		switch {
+		case !ok:
+			if err := addImportedPrompt(tx, p.Name, p); err != nil {
+				return changes, err
+			}
+			changes.Added = append(changes.Added, p.Name)
+		case existing.Prompt != p.Prompt || existing.Tags != p.Tags || existing.Description != p.Description:
+			if err := updateImportedPrompt(tx, p); err != nil {
+				return changes, err
+			}
+			changes.Updated = append(changes.Updated, p.Name)
+		}
+	}
+	for _, p := range current {
+		if _, ok := seen[p.Name]; !ok {
+			if err := tx.DeletePrompt(p.Name); err != nil {
+				return changes, err
+			}
+			changes.Deleted = append(changes.Deleted, p.Name)
+		}
+	}
+
+	return changes, tx.Commit()
+}
+
+// InitGitSync prepares dir as the sync repository, cloning remote into it when given.
+// Prompts already in the repository are imported with opts, so conflicts show up in the returned plan.
+func (a *App) InitGitSync(dir, remote string, opts ImportOptions) ([]ImportPlanEntry, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving directory: %w", err)
+	}
+	repo := gitRepo{dir: dir}
+
+	entries, _ := os.ReadDir(dir)
+	switch {
+	case remote != "" && len(entries) == 0:
+		if err := os.MkdirAll(filepath.Dir(dir), 0o700); err != nil {
+			return nil, fmt.Errorf("error creating directory: %w", err)
+		}
+		if _, err := (gitRepo{dir: filepath.Dir(dir)}).run("clone", "-q", remote, dir); err != nil {
+			return nil, err
+		}
+	default:
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("error creating directory: %w", err)
+		}
+		if !repo.succeeds("rev-parse", "--is-inside-work-tree") {
+			if _, err := repo.run("init", "-q"); err != nil {
+				return nil, err
+			}
+		}
+		if remote != "" {
+			if _, err := repo.run("remote", "add", "origin", remote); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := a.promptStore.SetSetting(settingSyncGitDir, dir); err != nil {
+		return nil, err
+	}
+
+	existing, err := ReadMarkdownDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var plan []ImportPlanEntry
+	if len(existing) > 0 {
+		if plan, err = a.ImportPrompts(existing, opts); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.promptStore.SetSetting(settingSyncGitApplied, repo.head()); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// SyncGit commits library changes to the sync repository, merges the remote,
+// imports the merged result, and pushes it back. When the merge conflicts it stops
+// and reports the conflicted prompts; resolve them with ResolveGitConflict and sync again.
+func (a *App) SyncGit() (*GitSyncResult, error) {
+	dir, err := a.promptStore.GetSetting(settingSyncGitDir)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("sync is not set up, run 'p sync init <dir>' first")
+	}
+	repo := gitRepo{dir: dir}
+	result := &GitSyncResult{}
+
+	branch, err := repo.branch()
+	if err != nil {
+		return nil, err
+	}
+
+	if !repo.mergeInProgress() {
+		// A commit that was never imported, such as one pulled by a sync that
+		// failed afterwards, is imported first; mirroring the library over it
+		// would revert it
+		applied, err := a.promptStore.GetSetting(settingSyncGitApplied)
+		if err != nil {
+			return nil, err
+		}
+		if head := repo.head(); applied != "" && head != "" && head != applied {
+			if result.Imported, err = a.applyGitRepo(repo); err != nil {
+				return nil, err
+			}
+		}
+
+		prompts, err := a.ListPrompts("")
+		if err != nil {
+			return nil, err
+		}
+		if err := mirrorMarkdownDir(dir, prompts); err != nil {
+			return nil, err
+		}
+		if result.Commit, err = repo.commitAll(); err != nil {
+			return nil, err
+		}
+
+		if repo.hasRemote() {
+			remoteBranch, err := repo.remoteHasBranch(branch)
+			if err != nil {
+				return nil, err
+			}
+			if remoteBranch {
+				// A failed pull is expected on conflicts; the merge state below tells them apart
+				_, pullErr := repo.run("pull", "-q", "--no-rebase", "--no-edit", "origin", branch)
+				if pullErr != nil && !repo.mergeInProgress() {
+					return nil, pullErr
+				}
+				result.Pulled = true
+			}
+		}
+	}
+
+	if repo.mergeInProgress() {
+		conflicts, err := repo.unmergedFiles()
+		if err != nil {
+			return nil, err
+		}
+		if len(conflicts) > 0 {
+			for _, file := range conflicts {
+				result.Conflicts = append(result.Conflicts, promptNameFromFile(file))
+			}
+			return result, nil
+		}
+		if _, err := repo.run("commit", "-q", "--no-edit"); err != nil {
+			return nil, err
+		}
+	}
+
+	imported, err := a.applyGitRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+	result.Imported = mergeChanges(result.Imported, imported)
+
+	if repo.hasRemote() && repo.succeeds("rev-parse", "-q", "--verify", "HEAD") {
+		if _, err := repo.run("push", "-q", "-u", "origin", branch); err != nil {
+			return nil, err
+		}
+		result.Pushed = true
+	}
+	return result, nil
+}
+
+// applyGitRepo makes the library match the prompts committed in the sync
+// repository and records HEAD as applied.
+func (a *App) applyGitRepo(repo gitRepo) (SyncChanges, error) {
+	prompts, err := ReadMarkdownDir(repo.dir)
+	if err != nil {
+		return SyncChanges{}, err
+	}
+	changes, err := a.applyPrompts(prompts)
+	if err != nil {
+		return changes, err
+	}
+	return changes, a.promptStore.SetSetting(settingSyncGitApplied, repo.head())
+}
+
+// mergeChanges appends the changes of b to a.
+func mergeChanges(a, b SyncChanges) SyncChanges {
+	return SyncChanges{
+		Added:   append(a.Added, b.Added...),
+		Updated: append(a.Updated, b.Updated...),
+		Deleted: append(a.Deleted, b.Deleted...),
+	}
+}
+
+// ResolveGitConflict settles a conflicted prompt file by keeping the local or remote side.
+// If the kept side deleted the prompt, the file is removed.
+func (a *App) ResolveGitConflict(name string, keepRemote bool) error {
+	dir, err := a.promptStore.GetSetting(settingSyncGitDir)
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		return fmt.Errorf("sync is not set up, run 'p sync init <dir>' first")
+	}
+	repo := gitRepo{dir: dir}
+
+	file := name
+	if filepath.Ext(file) != ".md" {
+		file = promptFileName(name)
+	}
+	conflicts, err := repo.unmergedFiles()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, c := range conflicts {
+		found = found || c == file
+	}
+	if !found {
+		return fmt.Errorf("sync conflict for prompt '%s' %w", name, ErrNotFound)
+	}
+
+	side := "--ours"
+	if keepRemote {
+		side = "--theirs"
+	}
+	if _, err := repo.run("checkout", side, "--", file); err != nil {
+		// The kept side deleted the prompt
+		_, err = repo.run("rm", "-q", "--", file)
+		return err
+	}
+	_, err = repo.run("add", "--", file)
+	return err
+}
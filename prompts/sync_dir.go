@@ -0,0 +1,323 @@
+package prompts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Values for SyncDir's prefer argument, which settles prompts changed on both sides.
+const (
+	PreferNone    = ""        // report both-sided changes as conflicts
+	PreferLibrary = "library" // keep the SQLite library's version
+	PreferFolder  = "folder"  // keep the folder's version
+	PreferNewer   = "newer"   // keep whichever side was modified last
+)
+
+// dirSyncBaseDir is where SyncDir keeps a copy of each prompt as last synced,
+// inside the synced folder, so conflicts can show the base version while the
+// library stores only its hash. Hidden directories are not read as prompts.
+const dirSyncBaseDir = ".p-sync"
+
+// DirSyncConflict holds the three versions of a prompt changed on both sides since the last sync.
+// Each version is the prompt's Markdown rendering, or empty if that side has no such prompt.
+// Base is also empty if the folder's copy of it is missing or out of date.
+type DirSyncConflict struct {
+	Name    string
+	Base    string
+	Library string
+	Folder  string
+}
+
+// DirSyncResult describes the outcome of a directory sync.
+type DirSyncResult struct {
+	ToLibrary SyncChanges // folder changes applied to the library
+	ToFolder  SyncChanges // library changes written to the folder
+	Conflicts []DirSyncConflict
+}
+
+// dirSide is one side's version of a prompt during a directory sync.
+type dirSide struct {
+	prompt   *Prompt
+	content  string // canonical Markdown, empty if absent
+	hash     string // contentHash of content
+	modified time.Time
+	fileName string // slash-separated path relative to the folder
+}
+
+// contentHash is the SHA-256 of a prompt's canonical Markdown, as recorded in
+// the sync state. An absent prompt has an empty hash.
+func contentHash(content string) string {
+	if content == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalMarkdown renders a prompt the way sync writes it, so both sides compare byte for byte.
+func canonicalMarkdown(p Prompt) (string, error) {
+	data, err := marshalMarkdown(p, false)
+	return string(data), err
+}
+
+// SyncDir keeps the library and a folder of Markdown files in two-way sync.
+// Each side is compared with the hash recorded at the last sync: a side that changed
+// wins, and a prompt changed on both sides is a conflict unless prefer settles it.
+// On the first sync of a prompt present on both sides, the more recently modified side wins.
+func (a *App) SyncDir(dir, prefer string) (*DirSyncResult, error) {
+	switch prefer {
+	case PreferNone, PreferLibrary, PreferFolder, PreferNewer:
+	default:
+		return nil, invalidf("invalid preference '%s', must be library, folder, or newer", prefer)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating directory: %w", err)
+	}
+
+	library, err := a.libraryVersions()
+	if err != nil {
+		return nil, err
+	}
+	folder, err := folderVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+	base, err := a.promptStore.GetDirSyncState(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{})
+	for _, m := range []map[string]dirSide{library, folder} {
+		for name := range m {
+			names[name] = struct{}{}
+		}
+	}
+	for name := range base {
+		names[name] = struct{}{}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	result := &DirSyncResult{}
+	var toLibrary []dirSide
+	newBase := make(map[string]string)
+	for _, name := range sorted {
+		lib, fold, baseHash := library[name], folder[name], base[name]
+		libChanged := lib.hash != baseHash
+		foldChanged := fold.hash != baseHash
+
+		winner := ""
+		switch {
+		case !libChanged && !foldChanged:
+			continue
+		case lib.content == fold.content:
+			newBase[name] = lib.content
+			continue
+		case libChanged && !foldChanged:
+			winner = PreferLibrary
+		case foldChanged && !libChanged:
+			winner = PreferFolder
+		default:
+			winner = prefer
+			if winner == PreferNone && baseHash == "" {
+				winner = PreferNewer
+			}
+			if winner == PreferNewer {
+				winner = newerSide(lib, fold)
+			}
+		}
+
+		switch winner {
+		case PreferLibrary:
+			if err := writeDirSide(dir, name, lib, fold, &result.ToFolder); err != nil {
+				return nil, err
+			}
+			newBase[name] = lib.content
+		case PreferFolder:
+			toLibrary = append(toLibrary, fold)
+			recordChange(&result.ToLibrary, name, lib, fold)
+			newBase[name] = fold.content
+		default:
+			result.Conflicts = append(result.Conflicts, DirSyncConflict{Name: name, Base: readDirSyncBase(dir, name, baseHash), Library: lib.content, Folder: fold.content})
+		}
+	}
+
+	// Files are written first so a failure below never leaves the sync state ahead of
+	// the folder; a base copy left ahead of the state fails its hash check and is ignored
+	for name, content := range newBase {
+		if err := writeDirSyncBase(dir, name, content); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := a.promptStore.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, side := range toLibrary {
+		if err := applyDirSide(tx, side); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range result.ToLibrary.Deleted {
+		if err := tx.DeletePrompt(name); err != nil {
+			return nil, err
+		}
+	}
+	for name, content := range newBase {
+		if err := tx.SetDirSyncState(dir, name, contentHash(content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// libraryVersions renders every prompt in the library, keyed by name.
+func (a *App) libraryVersions() (map[string]dirSide, error) {
+	prompts, err := a.ListPrompts("")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFileNames(prompts); err != nil {
+		return nil, err
+	}
+	versions := make(map[string]dirSide, len(prompts))
+	for i := range prompts {
+		content, err := canonicalMarkdown(prompts[i])
+		if err != nil {
+			return nil, err
+		}
+		versions[prompts[i].Name] = dirSide{prompt: &prompts[i], content: content, hash: contentHash(content), modified: prompts[i].UpdatedAt}
+	}
+	return versions, nil
+}
+
+// folderVersions parses every Markdown file in dir and its namespace subdirectories, keyed by prompt name.
+func folderVersions(dir string) (map[string]dirSide, error) {
+	files, err := markdownFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]dirSide)
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+
+		p, err := unmarshalMarkdown(data, promptNameFromFile(file))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		if err := validateImportedPrompt(&p); err != nil {
+			return nil, fmt.Errorf("invalid prompt in %s: %w", file, err)
+		}
+		if other, ok := versions[p.Name]; ok {
+			return nil, fmt.Errorf("%s and %s both define prompt '%s'", other.fileName, file, p.Name)
+		}
+		content, err := canonicalMarkdown(p)
+		if err != nil {
+			return nil, err
+		}
+		versions[p.Name] = dirSide{prompt: &p, content: content, hash: contentHash(content), modified: info.ModTime(), fileName: file}
+	}
+	return versions, nil
+}
+
+// writeDirSyncBase keeps content as the base copy of a prompt, or removes the copy when content is empty.
+func writeDirSyncBase(dir, name, content string) error {
+	baseDir := filepath.Join(dir, dirSyncBaseDir)
+	file := promptFileName(name)
+	if content == "" {
+		if _, err := os.Stat(filepath.Join(baseDir, filepath.FromSlash(file))); os.IsNotExist(err) {
+			return nil
+		}
+		return removeMarkdownFile(baseDir, file)
+	}
+	return writeMarkdownFile(baseDir, file, []byte(content))
+}
+
+// readDirSyncBase returns the base copy of a prompt if it matches hash, or an empty string.
+func readDirSyncBase(dir, name, hash string) string {
+	data, err := os.ReadFile(filepath.Join(dir, dirSyncBaseDir, filepath.FromSlash(promptFileName(name))))
+	if err != nil || contentHash(string(data)) != hash {
+		return ""
+	}
+	return string(data)
+}
+
+// newerSide picks the side modified last. A deleted side never beats an edit.
+func newerSide(lib, fold dirSide) string {
+	switch {
+	case lib.prompt == nil:
+		return PreferFolder
+	case fold.prompt == nil:
+		return PreferLibrary
+	case fold.modified.After(lib.modified):
+		return PreferFolder
+	default:
+		return PreferLibrary
+	}
+}
+
+// recordChange adds name to the added, updated, or deleted list depending on which sides have it.
+func recordChange(c *SyncChanges, name string, from, to dirSide) {
+	switch {
+	case to.prompt == nil:
+		c.Deleted = append(c.Deleted, name)
+	case from.prompt == nil:
+		c.Added = append(c.Added, name)
+	default:
+		c.Updated = append(c.Updated, name)
+	}
+}
+
+// writeDirSide makes the folder match the library's version of a prompt.
+func writeDirSide(dir, name string, lib, fold dirSide, changes *SyncChanges) error {
+	recordChange(changes, name, fold, lib)
+	if lib.prompt == nil {
+		return removeMarkdownFile(dir, fold.fileName)
+	}
+	fileName := fold.fileName
+	if fileName == "" {
+		fileName = promptFileName(name)
+	}
+	return writeMarkdownFile(dir, fileName, []byte(lib.content))
+}
+
+// applyDirSide makes the library match the folder's version of a prompt. Deletions are applied by the caller.
+func applyDirSide(tx *SQLitePromptStore, fold dirSide) error {
+	if fold.prompt == nil {
+		return nil
+	}
+	existing, err := tx.FindPromptByName(fold.prompt.Name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return addImportedPrompt(tx, fold.prompt.Name, *fold.prompt)
+	}
+	return updateImportedPrompt(tx, *fold.prompt)
+}
@@ -0,0 +1,44 @@
+package prompts
+
+import (
+	"regexp"
+	"strings"
+)
+
+// templateVarPattern matches {{name}} placeholders, allowing spaces inside the braces.
+var templateVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// TemplateVariables returns the distinct variable names in content, in order of first use.
+func TemplateVariables(content string) []string {
+	var names []string
+	seen := make(map[string]struct{})
+	for _, match := range templateVarPattern.FindAllStringSubmatch(content, -1) {
+		if _, ok := seen[match[1]]; !ok {
+			seen[match[1]] = struct{}{}
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// Render replaces every {{name}} in content with vars[name].
+// It fails listing all missing variables rather than leaving placeholders behind.
+func Render(content string, vars map[string]string) (string, error) {
+	var missing []string
+	for _, name := range TemplateVariables(content) {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", invalidf("missing template variables: %s", strings.Join(missing, ", "))
+	}
+	return templateVarPattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		return vars[templateVarPattern.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
+
+// HighlightVariables passes every {{name}} placeholder in content through style, leaving other text as is.
+func HighlightVariables(content string, style func(string) string) string {
+	return templateVarPattern.ReplaceAllStringFunc(content, style)
+}
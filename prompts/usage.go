@@ -0,0 +1,176 @@
+package prompts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// UsageKind is how a prompt was used.
+type UsageKind string
+
+const (
+	UsageGet    UsageKind = "get"    // shown, e.g. by p get or the API
+	UsageCopy   UsageKind = "copy"   // copied to the clipboard by a client
+	UsageRender UsageKind = "render" // rendered with template variables
+	UsageRun    UsageKind = "run"    // sent to a model by a client
+)
+
+// UsageKinds lists the valid usage kinds.
+var UsageKinds = []UsageKind{UsageGet, UsageCopy, UsageRender, UsageRun}
+
+// ParseUsageKind validates a usage kind name.
+func ParseUsageKind(s string) (UsageKind, error) {
+	for _, k := range UsageKinds {
+		if string(k) == s {
+			return k, nil
+		}
+	}
+	return "", invalidf("unknown usage kind '%s' (use get, copy, render, or run)", s)
+}
+
+// frecencyBuckets weight each use by its age, so recent uses count for more
+// than old ones and a prompt used daily outranks one used often long ago.
+var frecencyBuckets = []struct {
+	age    time.Duration
+	weight int
+}{
+	{4 * 24 * time.Hour, 100},
+	{14 * 24 * time.Hour, 70},
+	{31 * 24 * time.Hour, 50},
+	{90 * 24 * time.Hour, 30},
+}
+
+// frecencyOldWeight is the weight of uses older than every bucket.
+const frecencyOldWeight = 10
+
+// sqliteTimeLayout is how SQLite's CURRENT_TIMESTAMP stores times, in UTC.
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// Usage summarises how a prompt has been used.
+type Usage struct {
+	Count    int
+	LastUsed time.Time
+	Score    int // frecency: the age-weighted sum of uses
+}
+
+// PromptUsage is a prompt with its usage.
+type PromptUsage struct {
+	Prompt
+	Usage
+}
+
+// RecordUsage logs a use of the named prompt now.
+func (s *SQLitePromptStore) RecordUsage(name string, kind UsageKind) error {
+	return s.RecordUsageAt(name, kind, time.Now())
+}
+
+// RecordUsageAt logs a use of the named prompt at a given time.
+func (s *SQLitePromptStore) RecordUsageAt(name string, kind UsageKind, at time.Time) error {
+	query := "INSERT INTO usage_events (prompt_id, kind, used_at) SELECT id, ?, ? FROM prompts WHERE name = ?"
+	result, err := s.q.Exec(query, string(kind), at.UTC().Format(sqliteTimeLayout), name)
+	if err != nil {
+		return fmt.Errorf("error recording usage: %w", err)
+	}
+	return requireRow(result, name)
+}
+
+// UsageByPrompt returns the usage of every prompt that has been used, keyed by prompt ID.
+func (s *SQLitePromptStore) UsageByPrompt(now time.Time) (map[int]Usage, error) {
+	// Build the weight as a CASE over the age buckets
+	score := "CASE"
+	var args []any
+	for _, b := range frecencyBuckets {
+		score += " WHEN used_at >= ? THEN ?"
+		args = append(args, now.Add(-b.age).UTC().Format(sqliteTimeLayout), b.weight)
+	}
+	score += fmt.Sprintf(" ELSE %d END", frecencyOldWeight)
+
+	query := "SELECT prompt_id, COUNT(*), MAX(used_at), SUM(" + score + ") FROM usage_events GROUP BY prompt_id"
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error reading usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[int]Usage)
+	for rows.Next() {
+		var id int
+		var u Usage
+		var last string
+		if err := rows.Scan(&id, &u.Count, &last, &u.Score); err != nil {
+			return nil, fmt.Errorf("error scanning usage: %w", err)
+		}
+		if u.LastUsed, err = time.Parse(sqliteTimeLayout, last); err != nil {
+			return nil, fmt.Errorf("error parsing usage time: %w", err)
+		}
+		usage[id] = u
+	}
+	return usage, rows.Err()
+}
+
+// RecordUsage logs a use of the named prompt, for frecency ranking.
+func (a *App) RecordUsage(name string, kind UsageKind) error {
+	if _, err := ParseUsageKind(string(kind)); err != nil {
+		return err
+	}
+	return a.promptStore.RecordUsage(name, kind)
+}
+
+// SortByFrecency orders prompts by frecency, most used first. Ties keep their order.
+func (a *App) SortByFrecency(list []Prompt) error {
+	usage, err := a.promptStore.UsageByPrompt(time.Now())
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return usage[list[i].ID].Score > usage[list[j].ID].Score
+	})
+	return nil
+}
+
+// RankPrompts orders prompts for picking one: pinned prompts first, then by frecency.
+func (a *App) RankPrompts(list []Prompt) error {
+	if err := a.SortByFrecency(list); err != nil {
+		return err
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Pinned && !list[j].Pinned
+	})
+	return nil
+}
+
+// usedPrompts returns every prompt that has been used, with its usage, ordered by less.
+func (a *App) usedPrompts(limit int, less func(a, b Usage) bool) ([]PromptUsage, error) {
+	usage, err := a.promptStore.UsageByPrompt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	list, err := a.ListPrompts("")
+	if err != nil {
+		return nil, err
+	}
+	var used []PromptUsage
+	for _, p := range list {
+		if u, ok := usage[p.ID]; ok {
+			used = append(used, PromptUsage{Prompt: p, Usage: u})
+		}
+	}
+	sort.SliceStable(used, func(i, j int) bool {
+		return less(used[i].Usage, used[j].Usage)
+	})
+	if limit > 0 && len(used) > limit {
+		used = used[:limit]
+	}
+	return used, nil
+}
+
+// RecentPrompts returns up to limit prompts, most recently used first. A limit of 0 means all.
+func (a *App) RecentPrompts(limit int) ([]PromptUsage, error) {
+	return a.usedPrompts(limit, func(a, b Usage) bool { return a.LastUsed.After(b.LastUsed) })
+}
+
+// TopPrompts returns up to limit prompts, highest frecency first. A limit of 0 means all.
+func (a *App) TopPrompts(limit int) ([]PromptUsage, error) {
+	return a.usedPrompts(limit, func(a, b Usage) bool { return a.Score > b.Score })
+}
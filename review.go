@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+	"github.com/yookibooki/p/prompts"
+)
+
+// diffContext is how many unchanged lines are shown around each changed line.
+const diffContext = 3
+
+var (
+	diffAddStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	diffDelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+)
+
+// reviewChoice is what the user decided after seeing the diff of an edit.
+type reviewChoice int
+
+const (
+	reviewSave reviewChoice = iota
+	reviewEdit
+	reviewDiscard
+)
+
+// formChanged reports whether after differs from the stored prompt.
+func formChanged(before, after promptForm) bool {
+	after.Tags = prompts.NormalizeTags(after.Tags)
+	before.Tags = prompts.NormalizeTags(before.Tags)
+	return before != after
+}
+
+// printEditDiff writes the field changes and a colored unified diff of the body.
+func printEditDiff(w io.Writer, before, after promptForm) {
+	field := func(label, old, new string) {
+		if old != new {
+			fmt.Fprintf(w, "%s: %s -> %s\n", label, diffDelStyle.Render(old), diffAddStyle.Render(new))
+		}
+	}
+	field("Name", before.Name, after.Name)
+	field("Tags", prompts.NormalizeTags(before.Tags), prompts.NormalizeTags(after.Tags))
+	field("Description", before.Description, after.Description)
+	if before.Body == after.Body {
+		return
+	}
+
+	diff := prompts.DiffLines(before.Body, after.Body)
+	// Show unchanged lines only within diffContext of a change
+	visible := make([]bool, len(diff))
+	for i, line := range diff {
+		if line.Op == prompts.DiffEqual {
+			continue
+		}
+		for j := max(i-diffContext, 0); j <= min(i+diffContext, len(diff)-1); j++ {
+			visible[j] = true
+		}
+	}
+	fmt.Fprintln(w, diffDelStyle.Render("--- "+before.Name+" (saved)"))
+	fmt.Fprintln(w, diffAddStyle.Render("+++ "+after.Name+" (edited)"))
+	hidden := 0
+	for i, line := range diff {
+		if !visible[i] {
+			hidden++
+			continue
+		}
+		if hidden > 0 {
+			fmt.Fprintln(w, hintStyle.Render(fmt.Sprintf("  ⋮ %d unchanged lines", hidden)))
+			hidden = 0
+		}
+		switch line.Op {
+		case prompts.DiffDelete:
+			fmt.Fprintln(w, diffDelStyle.Render("-"+line.Text))
+		case prompts.DiffInsert:
+			fmt.Fprintln(w, diffAddStyle.Render("+"+line.Text))
+		default:
+			fmt.Fprintln(w, " "+line.Text)
+		}
+	}
+	if hidden > 0 {
+		fmt.Fprintln(w, hintStyle.Render(fmt.Sprintf("  ⋮ %d unchanged lines", hidden)))
+	}
+}
+
+// askReview asks whether to save the reviewed edit, go back to editing, or discard it.
+func askReview(reader *bufio.Reader, w io.Writer) (reviewChoice, error) {
+	for {
+		fmt.Fprint(w, "[s]ave, [e]dit again, [d]iscard? ")
+		answer, err := reader.ReadString('\n')
+		if err != nil && answer == "" {
+			return reviewDiscard, fmt.Errorf("error reading answer: %w", err)
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "s", "save", "y", "yes":
+			return reviewSave, nil
+		case "e", "edit":
+			return reviewEdit, nil
+		case "d", "discard", "n", "no":
+			return reviewDiscard, nil
+		}
+	}
+}
+
+// reviewedContent wraps an external editor so each edit is shown as a diff
+// against existing, with tags as the new tags, before it is saved. Content is
+// compared as app would store it. Editing again reopens the editor at the first changed line.
+func reviewedContent(cmd *cobra.Command, app *prompts.App, editor externalEditor, existing *prompts.Prompt, tags string) prompts.ContentProvider {
+	reader := bufio.NewReader(cmd.InOrStdin())
+	before := promptForm{Name: existing.Name, Tags: existing.Tags, Body: existing.Prompt}
+	return prompts.ContentFunc(func(initial string) (string, error) {
+		current := initial
+		for {
+			content, err := editor.Content(current)
+			// Quitting a second round of editing goes back to the edit under review
+			if errors.Is(err, prompts.ErrCancelled) && current != initial {
+				content, err = current, nil
+			}
+			if err != nil {
+				return "", err
+			}
+			normalized, err := app.NormalizeContent(content)
+			if err != nil {
+				return "", err
+			}
+			after := promptForm{Name: existing.Name, Tags: tags, Body: normalized}
+			if !formChanged(before, after) {
+				return content, nil
+			}
+
+			printEditDiff(cmd.OutOrStdout(), before, after)
+			choice, err := askReview(reader, cmd.OutOrStdout())
+			if err != nil {
+				return "", err
+			}
+			switch choice {
+			case reviewSave:
+				return content, nil
+			case reviewDiscard:
+				return "", prompts.ErrCancelled
+			}
+			current = content
+			editor.line = firstChangedLine(existing.Prompt, normalized)
+		}
+	})
+}
+
+// firstChangedLine returns the 1-based line of after where it first differs from before.
+func firstChangedLine(before, after string) int {
+	line := 1
+	for _, d := range prompts.DiffLines(before, after) {
+		if d.Op != prompts.DiffEqual {
+			return line
+		}
+		line++
+	}
+	return line
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yookibooki/p/prompts"
+)
+
+// serverOptions configures the HTTP API.
+type serverOptions struct {
+	Addr        string   // address the server listens on; requests must name it or a loopback host
+	Token       string   // required bearer token; empty disables auth
+	CORSOrigins []string // origins allowed to call the API from a browser; "*" allows any
+}
+
+// apiPrompt is the JSON shape of a prompt in the HTTP API.
+type apiPrompt struct {
+	Name        string    `json:"name"`
+	Prompt      string    `json:"prompt"`
+	Tags        []string  `json:"tags"`
+	Description string    `json:"description"`
+	Pinned      bool      `json:"pinned"`
+	Variables   []string  `json:"variables"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// apiTag is the JSON shape of a tag and how many prompts use it.
+type apiTag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func toAPIPrompt(p prompts.Prompt) apiPrompt {
+	tags := prompts.SplitTags(p.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	vars := prompts.TemplateVariables(p.Prompt)
+	if vars == nil {
+		vars = []string{}
+	}
+	return apiPrompt{Name: p.Name, Prompt: p.Prompt, Tags: tags, Description: p.Description, Pinned: p.Pinned, Variables: vars, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt}
+}
+
+// newServer returns the HTTP handler for the prompt API.
+func newServer(app *prompts.App, opts serverOptions) http.Handler {
+	s := &server{app: app}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/prompts", s.handleList)
+	mux.HandleFunc("POST /api/prompts", s.handleCreate)
+	mux.HandleFunc("GET /api/prompts/{name...}", s.handleGet)
+	mux.HandleFunc("PUT /api/prompts/{name...}", s.handleUpdate)
+	mux.HandleFunc("DELETE /api/prompts/{name...}", s.handleDelete)
+	mux.HandleFunc("POST /api/render/{name...}", s.handleRender)
+	mux.HandleFunc("POST /api/usage/{name...}", s.handleUsage)
+	mux.HandleFunc("GET /api/tags", s.handleTags)
+	mux.HandleFunc("GET /api/tags/{tag}", s.handleTagPrompts)
+	return withHost(opts.Addr, withCORS(opts.CORSOrigins, withAuth(opts.Token, mux)))
+}
+
+// server holds the handlers for the prompt API.
+type server struct {
+	app *prompts.App
+}
+
+// withHost rejects requests whose Host header names neither a loopback host
+// nor addr, so a web page cannot reach the API through DNS rebinding.
+func withHost(addr string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(r.Host, addr) {
+			writeError(w, http.StatusForbidden, "host '"+r.Host+"' not allowed")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether host, a Host header value, is a loopback host
+// or the host the server listens on.
+func allowedHost(host, addr string) bool {
+	if host == addr {
+		return true
+	}
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+	if strings.EqualFold(name, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(name); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	listen, _, err := net.SplitHostPort(addr)
+	if err != nil || listen == "" {
+		return false
+	}
+	if ip := net.ParseIP(listen); ip != nil && ip.IsUnspecified() {
+		return false
+	}
+	return strings.EqualFold(name, listen)
+}
+
+// withAuth requires "Authorization: Bearer <token>" on every request when token is set.
+func withAuth(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withCORS adds CORS headers for allowed origins and answers preflight requests before auth runs.
+func withCORS(origins []string, next http.Handler) http.Handler {
+	if len(origins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		for _, allowed := range origins {
+			if allowed == "*" || allowed == origin {
+				w.Header().Set("Access-Control-Allow-Origin", allowed)
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Add("Vary", "Origin")
+				break
+			}
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// writeAppError writes err with the HTTP status for its error class.
+func writeAppError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, prompts.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, prompts.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, prompts.ErrDuplicate):
+		status = http.StatusConflict
+	}
+	writeError(w, status, err.Error())
+}
+
+// maxBodyBytes caps request bodies. Prompts are far smaller, so anything
+// larger is a mistake or abuse and is not read into memory.
+const maxBodyBytes = 1 << 20
+
+// decodeBody decodes a JSON request body into v, rejecting unknown fields.
+// On failure it writes a 400, a 413 for a body over maxBodyBytes, or a 415
+// for a body that is not application/json.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "request body must be application/json")
+		return false
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return false
+		}
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+// findPrompt looks up the prompt named in the URL, writing a 404 if it does not exist.
+func (s *server) findPrompt(w http.ResponseWriter, r *http.Request) (*prompts.Prompt, bool) {
+	p, err := s.app.FindPrompt(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if p == nil {
+		writeError(w, http.StatusNotFound, "prompt '"+r.PathValue("name")+"' not found")
+		return nil, false
+	}
+	return p, true
+}
+
+// handleList serves GET /api/prompts, filtered by the optional tags and q query parameters.
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	list, err := s.app.FilterPrompts(prompts.PromptFilter{Tags: r.URL.Query().Get("tags"), Text: r.URL.Query().Get("q")})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	result := make([]apiPrompt, len(list))
+	for i, p := range list {
+		result[i] = toAPIPrompt(p)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *server) handleGet(w http.ResponseWriter, r *http.Request) {
+	if p, ok := s.findPrompt(w, r); ok {
+		// Usage is only for ranking, so failing to record it does not fail the request
+		_ = s.app.RecordUsage(p.Name, prompts.UsageGet)
+		writeJSON(w, http.StatusOK, toAPIPrompt(*p))
+	}
+}
+
+// promptBody is the request body for creating and updating prompts.
+type promptBody struct {
+	Name   string   `json:"name"`
+	Prompt string   `json:"prompt"`
+	Tags   []string `json:"tags"`
+}
+
+func (s *server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var body promptBody
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	p, err := s.app.CreatePrompt(body.Name, body.Prompt, strings.Join(body.Tags, ","))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toAPIPrompt(*p))
+}
+
+// handleUpdate serves PUT /api/prompts/{name}. Omitted fields keep their current value.
+func (s *server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	existing, ok := s.findPrompt(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Prompt *string   `json:"prompt"`
+		Tags   *[]string `json:"tags"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	content, tags := existing.Prompt, existing.Tags
+	if body.Prompt != nil {
+		content = *body.Prompt
+	}
+	if body.Tags != nil {
+		tags = strings.Join(*body.Tags, ",")
+	}
+	if _, err := s.app.EditPrompt(existing, content, tags); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	updated, ok := s.findPrompt(w, r)
+	if ok {
+		writeJSON(w, http.StatusOK, toAPIPrompt(*updated))
+	}
+}
+
+func (s *server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.findPrompt(w, r)
+	if !ok {
+		return
+	}
+	if err := s.app.DeletePrompt(p.Name); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRender serves POST /api/render/{name} with a body of {"variables": {...}}.
+func (s *server) handleRender(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.findPrompt(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Variables map[string]string `json:"variables"`
+	}
+	if r.ContentLength != 0 {
+		if !decodeBody(w, r, &body) {
+			return
+		}
+	}
+	rendered, err := prompts.Render(p.Prompt, body.Variables)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	_ = s.app.RecordUsage(p.Name, prompts.UsageRender)
+	writeJSON(w, http.StatusOK, map[string]string{"name": p.Name, "rendered": rendered})
+}
+
+// handleUsage serves POST /api/usage/{name} with a body of {"kind": "copy"}, letting
+// clients report uses such as copying or running a prompt for frecency ranking.
+func (s *server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.findPrompt(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Kind string `json:"kind"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	kind, err := prompts.ParseUsageKind(body.Kind)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	if err := s.app.RecordUsage(p.Name, kind); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTags serves GET /api/tags with the number of prompts using each tag.
+func (s *server) handleTags(w http.ResponseWriter, r *http.Request) {
+	counts, err := s.app.TagCounts()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	tags := make([]apiTag, len(counts))
+	for i, c := range counts {
+		tags[i] = apiTag{Name: c.Name, Count: c.Count}
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+// handleTagPrompts serves GET /api/tags/{tag}, the prompts carrying a tag.
+func (s *server) handleTagPrompts(w http.ResponseWriter, r *http.Request) {
+	list, err := s.app.ListPrompts(r.PathValue("tag"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	result := make([]apiPrompt, len(list))
+	for i, p := range list {
+		result[i] = toAPIPrompt(p)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -2,80 +2,252 @@ package main
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
+	"io"
+	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	ta "github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/yookibooki/p/prompts"
 )
 
-// LaunchExternalEditor opens an external editor to capture prompt content.
-func LaunchExternalEditor(initialContent string) (string, error) {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		defaultEditors := []string{"vim", "nano", "vi"} // Easy to add more, like "emacs"
-		for _, e := range defaultEditors {
-			if path, err := exec.LookPath(e); err == nil {
-				editor = path
-				break
-			}
-		}
-	}
-	if editor == "" {
-		return "", fmt.Errorf("EDITOR environment variable not set and no default editor (vim, nano, vi) found")
-	}
+// editResult says how an interactive edit ended.
+type editResult int
 
-	tmpfile, err := os.CreateTemp(os.TempDir(), "p-prompt-*.txt")
-	if err != nil {
-		return "", fmt.Errorf("could not create temporary file: %w", err)
-	}
-	defer os.Remove(tmpfile.Name())
+const (
+	editSaved     editResult = iota // the user saved new content
+	editCancelled                   // the user quit without saving
+	editUnchanged                   // the user saved without changing anything
+)
 
-	if _, err := tmpfile.WriteString(initialContent); err != nil {
-		return "", fmt.Errorf("could not write to temporary file: %w", err)
+// editorContent adapts an editor's result to prompts.ContentProvider: a cancelled
+// edit is prompts.ErrCancelled and an unchanged one returns the initial content.
+func editorContent(content string, result editResult, err error) (string, error) {
+	if err != nil {
+		return "", err
 	}
-	if err := tmpfile.Close(); err != nil {
-		return "", fmt.Errorf("could not close temporary file: %w", err)
+	if result == editCancelled {
+		return "", prompts.ErrCancelled
 	}
+	return content, nil
+}
 
-	cmd := exec.Command(editor, tmpfile.Name())
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("editor command failed: %w", err)
-	}
+// promptForm holds the fields edited in the TUI form.
+type promptForm struct {
+	Name        string
+	Tags        string
+	Description string
+	Body        string
+}
 
-	editedContentBytes, err := os.ReadFile(tmpfile.Name())
-	if err != nil {
-		return "", fmt.Errorf("could not read edited content from temporary file: %w", err)
-	}
+// fields converts the form for prompts.App.SavePrompt.
+func (f promptForm) fields() prompts.PromptFields {
+	return prompts.PromptFields{Name: f.Name, Tags: f.Tags, Description: f.Description, Body: f.Body}
+}
 
-	return strings.TrimSpace(string(editedContentBytes)), nil
+// formOptions configures the TUI form.
+type formOptions struct {
+	AllTags []string          // known tags offered as completions
+	Exists  func(string) bool // reports whether a name belongs to another prompt
 }
 
-// editorModel represents the TUI editor state with a textarea and quit flag.
+// Form fields in Tab order. The single-line fields come first and index editorModel.inputs.
+const (
+	fieldName = iota
+	fieldTags
+	fieldDescription
+	fieldBody
+)
+
+var fieldLabels = [fieldBody]string{"Name", "Tags", "Description"}
+
+var (
+	labelStyle = lipgloss.NewStyle().Width(labelWidth).Foreground(lipgloss.Color("241"))
+	hintStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	okStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	errStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	varStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Bold(true)
+	matchStyle = lipgloss.NewStyle().Background(lipgloss.Color("58"))
+	barStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Background(lipgloss.Color("237"))
+)
+
+// Rows the editor draws around the textarea, used to size it to the terminal.
+const (
+	bodyOnlyChromeHeight = 4  // instructions and status bar
+	formChromeHeight     = 10 // instructions, three inputs, validation and status bar
+	labelWidth           = 13
+)
+
+// editorModel is the TUI editor state. In body-only mode it is a plain textarea;
+// otherwise it is a form with name, tags and description inputs above the body.
 type editorModel struct {
+	inputs   [fieldBody]textinput.Model
 	ta       ta.Model
+	focus    int
+	bodyOnly bool
+	opts     formOptions
+	initial  promptForm
+	width    int
+	height   int
+
+	// normalization is applied to the body before it is stored, so the size
+	// shown and validated is the size that will be saved
+	normalization prompts.Normalization
+
+	undo, redo []bodySnapshot
+	typing     bool // the last body change was typed text, which undo groups into words
+
+	bar        int                // which search bar is open
+	barInputs  [2]textinput.Model // find and replace
+	barFocus   int                // index into barInputs
+	notice     string             // result of the last search or replace
+	confirming bool               // asking whether to discard unsaved changes
+
+	saved    bool
 	quitting bool
 }
 
-// initialEditorModel creates a new editor model with the given initial content.
+// Search bars shown below the body.
+const (
+	barNone = iota
+	barFind
+	barReplace
+)
+
+// maxUndo caps the undo history.
+const maxUndo = 200
+
+// bodySnapshot is a body state in the undo history.
+type bodySnapshot struct {
+	value     string
+	line, col int
+}
+
+// initialEditorModel creates a body-only editor model with the given initial content.
 func initialEditorModel(initialContent string) editorModel {
+	m := newFormModel(promptForm{Body: initialContent}, formOptions{})
+	m.bodyOnly = true
+	m.setFocus(fieldBody)
+	m.resize(m.width, m.height)
+	return m
+}
+
+// newFormModel creates a form model prefilled from form, with the name field focused.
+func newFormModel(form promptForm, opts formOptions) editorModel {
 	txtArea := ta.New()
 	txtArea.Placeholder = "Enter your prompt..."
-	txtArea.Focus()
 	txtArea.CharLimit = 0
-	txtArea.SetWidth(80)
 	txtArea.Prompt = ""
-	txtArea.SetValue(initialContent)
+	// Variable highlighting resets styles mid-line, so the cursor line has no background to lose
+	txtArea.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	txtArea.SetValue(form.Body)
 
-	return editorModel{
-		ta: txtArea,
+	m := editorModel{ta: txtArea, opts: opts, initial: form}
+	for i, value := range [fieldBody]string{form.Name, form.Tags, form.Description} {
+		input := textinput.New()
+		input.Prompt = ""
+		input.CharLimit = 0
+		input.SetValue(value)
+		m.inputs[i] = input
 	}
+	m.inputs[fieldTags].Placeholder = "comma-separated"
+	m.inputs[fieldDescription].Placeholder = "optional"
+	for i, label := range []string{"Find: ", "Replace with: "} {
+		input := textinput.New()
+		input.Prompt = label
+		input.CharLimit = 0
+		m.barInputs[i] = input
+	}
+	m.setFocus(fieldName)
+	m.resize(80, 24)
+	return m
+}
+
+// resize fits the textarea and inputs to a terminal of the given size.
+func (m *editorModel) resize(width, height int) {
+	m.width, m.height = width, height
+	chrome := formChromeHeight
+	if m.bodyOnly {
+		chrome = bodyOnlyChromeHeight
+	}
+	chrome += m.bar // one line per search bar input
+	m.ta.SetWidth(max(width, 20))
+	m.ta.SetHeight(max(height-chrome, 3))
+	for i := range m.inputs {
+		m.inputs[i].Width = max(width-labelWidth-1, 10)
+	}
+}
+
+// setFocus moves keyboard focus to field.
+func (m *editorModel) setFocus(field int) {
+	m.focus = field
+	for i := range m.inputs {
+		if i == field {
+			m.inputs[i].Focus()
+		} else {
+			m.inputs[i].Blur()
+		}
+	}
+	if field == fieldBody {
+		m.ta.Focus()
+	} else {
+		m.ta.Blur()
+	}
+}
+
+// form returns the current field values.
+func (m editorModel) form() promptForm {
+	return promptForm{
+		Name:        strings.TrimSpace(m.inputs[fieldName].Value()),
+		Tags:        m.inputs[fieldTags].Value(),
+		Description: strings.TrimSpace(m.inputs[fieldDescription].Value()),
+		Body:        m.ta.Value(),
+	}
+}
+
+// problems lists what would stop the form from saving.
+func (m editorModel) problems() []string {
+	form := m.form()
+	var problems []string
+	if !m.bodyOnly {
+		if err := prompts.ValidatePromptName(form.Name); err != nil {
+			problems = append(problems, err.Error())
+		} else if m.opts.Exists != nil && m.opts.Exists(form.Name) {
+			problems = append(problems, fmt.Sprintf("a prompt named '%s' already exists", form.Name))
+		}
+		if err := prompts.ValidatePromptDescription(form.Description); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if err := prompts.ValidatePromptContent(m.normalization.Apply(form.Body)); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
+
+// tagCompletion returns the rest of the first known tag that extends the tag
+// being typed after the last comma, skipping tags already entered.
+func tagCompletion(value string, allTags []string) string {
+	parts := strings.Split(value, ",")
+	current := strings.TrimSpace(parts[len(parts)-1])
+	if current == "" {
+		return ""
+	}
+	entered := make(map[string]bool, len(parts))
+	for _, part := range parts[:len(parts)-1] {
+		entered[strings.TrimSpace(part)] = true
+	}
+	for _, tag := range allTags {
+		if strings.HasPrefix(tag, current) && tag != current && !entered[tag] {
+			return tag[len(current):]
+		}
+	}
+	return ""
 }
 
 // Init initializes the editor model and returns the blink command for the textarea.
@@ -85,48 +257,524 @@ func (m editorModel) Init() tea.Cmd {
 
 // Update handles key events and updates the editor model.
 func (m editorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		switch {
-		case msg.Type == tea.KeyEsc || msg.Type == tea.KeyCtrlC:
-			m.quitting = true
-			return m, tea.Quit
-		case msg.Type == tea.KeyCtrlD:
-			m.quitting = true
-			return m, tea.Quit
-		case msg.Type == tea.KeyEnter && msg.Alt:
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		m.resize(msg.Width, msg.Height)
+		return m, nil
+	}
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m.updateFocused(msg)
+	}
+
+	if m.confirming {
+		m.confirming = false
+		if keyMsg.String() == "y" {
 			m.quitting = true
 			return m, tea.Quit
 		}
+		return m, nil
+	}
+	if m.bar != barNone {
+		if model, cmd, handled := m.updateBar(keyMsg); handled {
+			return model, cmd
+		}
+	}
+
+	switch {
+	case keyMsg.Type == tea.KeyCtrlL:
+		m.ta.ShowLineNumbers = !m.ta.ShowLineNumbers
+		// The textarea only recomputes its text width in SetWidth
+		m.resize(m.width, m.height)
+		return m, nil
+	case keyMsg.Type == tea.KeyCtrlZ:
+		m.undoBody()
+		return m, nil
+	case keyMsg.Type == tea.KeyCtrlY:
+		m.redoBody()
+		return m, nil
+	case keyMsg.Type == tea.KeyCtrlF:
+		return m, m.openBar(barFind)
+	case keyMsg.Type == tea.KeyCtrlR:
+		return m, m.openBar(barReplace)
+	case keyMsg.Type == tea.KeyEsc:
+		if m.dirty() {
+			m.confirming = true
+			return m, nil
+		}
+		m.quitting = true
+		return m, tea.Quit
+	case keyMsg.Type == tea.KeyCtrlC:
+		m.quitting = true
+		return m, tea.Quit
+	case keyMsg.Type == tea.KeyCtrlD || (keyMsg.Type == tea.KeyEnter && keyMsg.Alt):
+		// The body-only editor leaves validation to the caller
+		if !m.bodyOnly && len(m.problems()) > 0 {
+			return m, nil
+		}
+		m.saved = true
+		m.quitting = true
+		return m, tea.Quit
+	case m.bodyOnly:
+	case keyMsg.Type == tea.KeyTab:
+		if m.focus == fieldTags {
+			if rest := tagCompletion(m.inputs[fieldTags].Value(), m.opts.AllTags); rest != "" {
+				m.inputs[fieldTags].SetValue(m.inputs[fieldTags].Value() + rest)
+				m.inputs[fieldTags].CursorEnd()
+				return m, nil
+			}
+		}
+		m.setFocus((m.focus + 1) % (fieldBody + 1))
+		return m, nil
+	case keyMsg.Type == tea.KeyShiftTab:
+		m.setFocus((m.focus + fieldBody) % (fieldBody + 1))
+		return m, nil
+	case keyMsg.Type == tea.KeyEnter && m.focus != fieldBody:
+		m.setFocus(m.focus + 1)
+		return m, nil
 	}
+	return m.updateFocused(msg)
+}
 
+// updateFocused passes msg to the focused field, recording body changes for undo.
+func (m editorModel) updateFocused(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
+	if m.focus != fieldBody {
+		m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+		return m, cmd
+	}
+
+	before := m.snapshot()
 	m.ta, cmd = m.ta.Update(msg)
+	if m.ta.Value() == before.value {
+		m.typing = false
+		return m, cmd
+	}
+	// Typed characters join the previous undo step until a space, newline, or other edit
+	keyMsg, _ := msg.(tea.KeyMsg)
+	typing := keyMsg.Type == tea.KeyRunes && !keyMsg.Paste && len(keyMsg.Runes) == 1 && !unicode.IsSpace(keyMsg.Runes[0])
+	if !typing || !m.typing {
+		m.pushUndo(before)
+	}
+	m.typing = typing
 	return m, cmd
 }
 
-// View renders the editor interface with instructions and textarea.
+// result says how the editor was closed.
+func (m editorModel) result() editResult {
+	switch {
+	case !m.saved:
+		return editCancelled
+	case !m.dirty():
+		return editUnchanged
+	}
+	return editSaved
+}
+
+// dirty reports whether any field differs from what the editor started with.
+func (m editorModel) dirty() bool {
+	form := m.form()
+	form.Name, form.Description = m.inputs[fieldName].Value(), m.inputs[fieldDescription].Value()
+	return form != m.initial
+}
+
+// snapshot captures the body and cursor position.
+func (m editorModel) snapshot() bodySnapshot {
+	info := m.ta.LineInfo()
+	return bodySnapshot{value: m.ta.Value(), line: m.ta.Line(), col: info.StartColumn + info.ColumnOffset}
+}
+
+// restore replaces the body with a snapshot and puts the cursor back where it was.
+func (m *editorModel) restore(snap bodySnapshot) {
+	m.ta.SetValue(snap.value)
+	m.moveCursor(snap.line, snap.col)
+}
+
+// moveCursor moves the body cursor to a rune column on a line.
+func (m *editorModel) moveCursor(line, col int) {
+	// SetValue leaves the cursor on the last line, and CursorUp steps through soft-wrapped rows
+	m.ta.CursorEnd()
+	for steps := 0; m.ta.Line() > line && steps < len(m.ta.Value()); steps++ {
+		m.ta.CursorUp()
+	}
+	for steps := 0; m.ta.Line() < line && steps < len(m.ta.Value()); steps++ {
+		m.ta.CursorDown()
+	}
+	m.ta.SetCursor(col)
+}
+
+func (m *editorModel) pushUndo(snap bodySnapshot) {
+	m.undo = append(m.undo, snap)
+	if len(m.undo) > maxUndo {
+		m.undo = m.undo[1:]
+	}
+	m.redo = nil
+}
+
+func (m *editorModel) undoBody() {
+	if len(m.undo) == 0 {
+		return
+	}
+	m.redo = append(m.redo, m.snapshot())
+	m.restore(m.undo[len(m.undo)-1])
+	m.undo = m.undo[:len(m.undo)-1]
+	m.typing = false
+}
+
+func (m *editorModel) redoBody() {
+	if len(m.redo) == 0 {
+		return
+	}
+	m.undo = append(m.undo, m.snapshot())
+	m.restore(m.redo[len(m.redo)-1])
+	m.redo = m.redo[:len(m.redo)-1]
+	m.typing = false
+}
+
+// openBar shows the find or find/replace bar with the find input focused.
+func (m *editorModel) openBar(bar int) tea.Cmd {
+	m.bar = bar
+	m.notice = ""
+	m.barFocus = 0
+	m.barInputs[1].Blur()
+	m.setFocus(fieldBody)
+	m.ta.Blur()
+	m.resize(m.width, m.height)
+	return m.barInputs[0].Focus()
+}
+
+func (m *editorModel) closeBar() {
+	m.bar = barNone
+	m.barInputs[0].Blur()
+	m.barInputs[1].Blur()
+	m.setFocus(fieldBody)
+	m.resize(m.width, m.height)
+}
+
+// updateBar handles keys while a search bar is open. handled is false for keys
+// the editor itself should act on, such as save.
+func (m editorModel) updateBar(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.closeBar()
+		return m, nil, true
+	case tea.KeyTab, tea.KeyShiftTab:
+		if m.bar == barReplace {
+			m.barInputs[m.barFocus].Blur()
+			m.barFocus = 1 - m.barFocus
+			return m, m.barInputs[m.barFocus].Focus(), true
+		}
+		return m, nil, true
+	case tea.KeyEnter:
+		if m.bar == barReplace && m.barFocus == 1 {
+			m.replaceAll()
+		} else {
+			m.findNext(true)
+		}
+		return m, nil, true
+	case tea.KeyCtrlC, tea.KeyCtrlD, tea.KeyCtrlZ, tea.KeyCtrlY, tea.KeyCtrlF, tea.KeyCtrlR:
+		return m, nil, false
+	}
+
+	query := m.barInputs[0].Value()
+	var cmd tea.Cmd
+	m.barInputs[m.barFocus], cmd = m.barInputs[m.barFocus].Update(msg)
+	if m.barFocus == 0 && m.barInputs[0].Value() != query {
+		// Incremental search: stay on the current match while it still matches
+		m.findNext(false)
+	}
+	return m, cmd, true
+}
+
+// findNext moves the body cursor to the next case-insensitive match of the find
+// input, wrapping at the end. With skipCurrent false a match at the cursor is kept.
+func (m *editorModel) findNext(skipCurrent bool) {
+	query := []rune(strings.ToLower(m.barInputs[0].Value()))
+	if len(query) == 0 {
+		m.notice = ""
+		return
+	}
+	text := []rune(strings.ToLower(m.ta.Value()))
+	lines := strings.Split(string(text), "\n")
+	cur := m.snapshot()
+
+	// Offsets count runes through the whole body, with one per newline
+	offset := cur.col
+	for _, line := range lines[:cur.line] {
+		offset += len([]rune(line)) + 1
+	}
+	if skipCurrent {
+		offset++
+	}
+	match := runeIndex(text, query, offset)
+	if match < 0 {
+		match = runeIndex(text, query, 0)
+	}
+	if match < 0 {
+		m.notice = "No matches"
+		return
+	}
+
+	line, col := 0, match
+	for col > len([]rune(lines[line])) {
+		col -= len([]rune(lines[line])) + 1
+		line++
+	}
+	m.moveCursor(line, col)
+	m.notice = fmt.Sprintf("%d matches", strings.Count(string(text), string(query)))
+}
+
+// runeIndex returns the rune index of the first occurrence of sub in s at or after from, or -1.
+func runeIndex(s, sub []rune, from int) int {
+	for i := max(from, 0); i+len(sub) <= len(s); i++ {
+		if string(s[i:i+len(sub)]) == string(sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// replaceAll replaces every case-insensitive match of the find input with the
+// replace input as a single undo step.
+func (m *editorModel) replaceAll() {
+	find, replacement := m.barInputs[0].Value(), m.barInputs[1].Value()
+	if find == "" {
+		return
+	}
+	pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(find))
+	before := m.snapshot()
+	n := len(pattern.FindAllStringIndex(before.value, -1))
+	if n == 0 {
+		m.notice = "No matches"
+		return
+	}
+	m.pushUndo(before)
+	m.restore(bodySnapshot{value: pattern.ReplaceAllLiteralString(before.value, replacement), line: before.line, col: before.col})
+	m.typing = false
+	m.notice = fmt.Sprintf("Replaced %d", n)
+}
+
+// statusBar summarises the body: cursor position, size, a rough token count
+// (about four characters per token), and how much of MaxPromptContentLen is left.
+// The size is of the body as it will be stored, measured like ValidatePromptContent.
+func (m editorModel) statusBar() string {
+	chars := len(m.normalization.Apply(m.ta.Value()))
+	left := prompts.MaxPromptContentLen - chars
+	budget := fmt.Sprintf("%d left", left)
+	if left < 0 {
+		budget = errStyle.Render(fmt.Sprintf("%d over", -left))
+	}
+	text := fmt.Sprintf(" Ln %d/%d, Col %d · %d chars · ~%d tokens · %s · Ctrl+L line numbers ",
+		m.ta.Line()+1, m.ta.LineCount(), m.ta.LineInfo().CharOffset+1, chars, (chars+3)/4, budget)
+	if m.notice != "" {
+		text += "· " + m.notice + " "
+	}
+	if m.confirming {
+		text = " Discard unsaved changes? (y/n) "
+	}
+	return barStyle.Width(max(m.width, lipgloss.Width(text))).Render(text)
+}
+
+// bodyView renders the textarea with {{variables}} and search matches highlighted.
+func (m editorModel) bodyView() string {
+	view := prompts.HighlightVariables(m.ta.View(), func(v string) string {
+		return varStyle.Render(v)
+	})
+	if query := m.barInputs[0].Value(); m.bar != barNone && query != "" {
+		pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+		view = highlightMatches(view, pattern, m.gutterWidth())
+	}
+	if m.bar != barNone {
+		view += "\n" + m.barInputs[0].View()
+		if m.bar == barReplace {
+			view += "\n" + m.barInputs[1].View()
+		}
+	}
+	return view
+}
+
+// gutterWidth is the number of cells the textarea draws before the text of
+// each line: the prompt and, when shown, the line number.
+func (m editorModel) gutterWidth() int {
+	width := utf8.RuneCountInString(m.ta.Prompt)
+	if m.ta.ShowLineNumbers {
+		width += len(strconv.Itoa(m.ta.MaxHeight)) + 2
+	}
+	return width
+}
+
+// highlightMatches styles the matches of pattern in the visible text of view,
+// a rendered textarea. Escape sequences are kept intact and never searched,
+// and neither are the first gutter cells of each line. Matches do not span lines.
+func highlightMatches(view string, pattern *regexp.Regexp, gutter int) string {
+	lines := strings.Split(view, "\n")
+	for i, line := range lines {
+		lines[i] = highlightLine(line, pattern, gutter)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// highlightLine does highlightMatches for a single line. Each highlighted run
+// ends with a reset, so the escape sequences in effect are written again after it.
+func highlightLine(line string, pattern *regexp.Regexp, gutter int) string {
+	var plain strings.Builder
+	for i := 0; i < len(line); {
+		if n := escapeLen(line[i:]); n > 0 {
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(line[i:])
+		plain.WriteString(line[i : i+size])
+		i += size
+	}
+	text := plain.String()
+	start := 0
+	for cells := 0; cells < gutter && start < len(text); cells++ {
+		_, size := utf8.DecodeRuneInString(text[start:])
+		start += size
+	}
+	matches := pattern.FindAllStringIndex(text[start:], -1)
+	if len(matches) == 0 {
+		return line
+	}
+	marked := make([]bool, len(text))
+	for _, match := range matches {
+		for j := match[0]; j < match[1]; j++ {
+			marked[start+j] = true
+		}
+	}
+
+	var out, run strings.Builder
+	var active []string
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		out.WriteString(matchStyle.Render(run.String()))
+		out.WriteString(strings.Join(active, ""))
+		run.Reset()
+	}
+	for i, pos := 0, 0; i < len(line); {
+		if n := escapeLen(line[i:]); n > 0 {
+			flush()
+			seq := line[i : i+n]
+			out.WriteString(seq)
+			if seq == "\x1b[0m" || seq == "\x1b[m" {
+				active = active[:0]
+			} else {
+				active = append(active, seq)
+			}
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(line[i:])
+		if marked[pos] {
+			run.WriteString(line[i : i+size])
+		} else {
+			flush()
+			out.WriteString(line[i : i+size])
+		}
+		i += size
+		pos += size
+	}
+	flush()
+	return out.String()
+}
+
+// escapeLen returns the length of the ANSI control sequence at the start of s,
+// or 0 if s does not start with one.
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' {
+		return 0
+	}
+	if s[1] != '[' {
+		return 2
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// View renders the editor interface with instructions, form fields, textarea and status bar.
 func (m editorModel) View() string {
-	return lipgloss.JoinVertical(lipgloss.Left,
-		"\n"+
-			"  "+lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render("Enter your prompt. Press Alt+Enter or Ctrl+D to save, Esc or Ctrl+C to cancel."),
-		"\n"+
-			m.ta.View(),
-	)
+	if m.bodyOnly {
+		return lipgloss.JoinVertical(lipgloss.Left,
+			"\n"+
+				"  "+lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render("Enter your prompt. Alt+Enter or Ctrl+D to save, Esc or Ctrl+C to cancel. Ctrl+Z/Ctrl+Y undo/redo, Ctrl+F find, Ctrl+R replace."),
+			"\n"+
+				m.bodyView(),
+			m.statusBar(),
+		)
+	}
+
+	lines := []string{
+		"",
+		"  " + lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render("Tab/Shift+Tab to move between fields. Ctrl+D to save, Esc or Ctrl+C to cancel. Ctrl+Z/Ctrl+Y undo/redo, Ctrl+F find, Ctrl+R replace."),
+		"",
+	}
+	for i, input := range m.inputs {
+		line := labelStyle.Render(fieldLabels[i]+":") + input.View()
+		if i == fieldTags && m.focus == fieldTags {
+			if rest := tagCompletion(input.Value(), m.opts.AllTags); rest != "" {
+				line += hintStyle.Render(" " + rest + " (Tab)")
+			}
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", m.bodyView(), "")
+	if problems := m.problems(); len(problems) > 0 {
+		lines = append(lines, errStyle.Render("✗ "+strings.Join(problems, " · ")))
+	} else {
+		lines = append(lines, okStyle.Render("✓ Ready to save"))
+	}
+	lines = append(lines, m.statusBar())
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// tuiEditor is a prompts.ContentProvider backed by the Bubble Tea editor.
+type tuiEditor struct {
+	in            io.Reader
+	out           io.Writer
+	normalization prompts.Normalization // the library's content normalization
+}
+
+// Content launches the Bubble Tea TUI for editing prompt content.
+func (e tuiEditor) Content(initialContent string) (string, error) {
+	return editorContent(e.Edit(initialContent))
+}
+
+// Edit launches the Bubble Tea TUI for editing prompt content.
+func (e tuiEditor) Edit(initialContent string) (string, editResult, error) {
+	m, err := e.run(initialEditorModel(initialContent))
+	if err != nil {
+		return "", editCancelled, err
+	}
+	return m.ta.Value(), m.result(), nil
+}
+
+// Form launches the Bubble Tea form for editing a whole prompt.
+func (e tuiEditor) Form(form promptForm, opts formOptions) (promptForm, editResult, error) {
+	m, err := e.run(newFormModel(form, opts))
+	if err != nil {
+		return form, editCancelled, err
+	}
+	return m.form(), m.result(), nil
 }
 
-// RunTUIEditor launches the Bubble Tea TUI for editing prompt content.
-func RunTUIEditor(initialContent string) (string, error) {
-	p := tea.NewProgram(initialEditorModel(initialContent))
+func (e tuiEditor) run(model editorModel) (editorModel, error) {
+	model.normalization = e.normalization
+	p := tea.NewProgram(model, tea.WithInput(e.in), tea.WithOutput(e.out))
 
 	m, err := p.Run()
 	if err != nil {
-		return "", fmt.Errorf("error running TUI editor: %w", err)
+		return model, fmt.Errorf("error running TUI editor: %w", err)
 	}
 
 	if m, ok := m.(editorModel); ok {
-		return m.ta.Value(), nil
+		return m, nil
 	}
 
-	return initialContent, nil
+	return model, nil
 }